@@ -1,11 +1,18 @@
 package config
 
-import "time"
+import (
+	"os"
+	"time"
+)
 
 type Config struct {
 	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`
 	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
 	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	MasterKey           string        `mapstructure:"MASTER_KEY"`
+	StoreBackend        string        `mapstructure:"STORE_BACKEND"`
+	DataDir             string        `mapstructure:"DATA_DIR"`
+	TallyKeyFile        string        `mapstructure:"TALLY_KEY_FILE"`
 }
 
 func LoadConfig() Config {
@@ -13,5 +20,20 @@ func LoadConfig() Config {
 	config.ServerAddress = ":8080"
 	config.TokenSymmetricKey = "12345678901234567890123456789012"
 	config.AccessTokenDuration = 150 * time.Minute
+	// the master key seals every stored key, so it has no default and is only read from the environment
+	config.MasterKey = os.Getenv("MASTER_KEY")
+	// memory, file or sqlite; an unknown backend stops the server when the store is opened
+	config.StoreBackend = getEnv("STORE_BACKEND", "memory")
+	config.DataDir = getEnv("DATA_DIR", "data")
+	// the public key of the tally, the operator keeps the private one
+	config.TallyKeyFile = getEnv("TALLY_KEY_FILE", "tally_public_key.pem")
 	return config
 }
+
+// getEnv returns the environment variable name, or fallback when it is not set or empty
+func getEnv(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
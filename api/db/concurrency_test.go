@@ -0,0 +1,75 @@
+package db_test
+
+import (
+	"fmt"
+	"github.com/EliriaT/CS-Labs/api/db"
+	"github.com/google/uuid"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestConcurrentWrites hammers every backend from many goroutines, to be run with -race.
+// Every username is registered by several goroutines at once and exactly one of them must win.
+func TestConcurrentWrites(t *testing.T) {
+	const (
+		workers   = 8
+		usernames = 10
+		messages  = 20
+	)
+
+	forEachBackend(t, func(t *testing.T, b backend, store db.Store, dir string) {
+		var wg sync.WaitGroup
+		wins := make([]int, usernames)
+		var winsMu sync.Mutex
+		errs := make(chan error, workers*(usernames+messages))
+
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				author := fmt.Sprintf("author%d", w)
+				for i := 0; i < usernames; i++ {
+					err := store.StoreUser(&db.User{Id: uuid.New(), Username: fmt.Sprintf("user%d", i)})
+					switch err {
+					case nil:
+						winsMu.Lock()
+						wins[i]++
+						winsMu.Unlock()
+					case db.ErrDuplicateUser:
+					default:
+						errs <- err
+					}
+				}
+				for i := 0; i < messages; i++ {
+					message := db.Message{Id: uuid.New(), Author: author, EncryptedMessage: []byte{byte(i)}, CreatedAt: time.Now()}
+					if err := store.StoreMessage(&message); err != nil {
+						errs <- err
+					}
+					if list, err := store.GetMessagesOfUser(author); err != nil || len(list) != i+1 {
+						errs <- fmt.Errorf("%s has %d messages after storing %d: %v", author, len(list), i+1, err)
+					}
+					// the messages of the other authors are read while they are written
+					store.GetMessagesOfUser(fmt.Sprintf("author%d", (w+1)%workers))
+				}
+			}(w)
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			t.Error(err)
+		}
+		for i, n := range wins {
+			if n != 1 {
+				t.Errorf("user%d was stored %d times", i, n)
+			}
+		}
+		for w := 0; w < workers; w++ {
+			list, err := store.GetMessagesOfUser(fmt.Sprintf("author%d", w))
+			if err != nil || len(list) != messages {
+				t.Errorf("author%d has %d messages: %v", w, len(list), err)
+			}
+		}
+	})
+}
@@ -0,0 +1,499 @@
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	logFileName = "store.log"
+	// the log is compacted when it holds compactionFactor times more records than there are live users, messages and grants
+	compactionFactor = 2
+	minCompaction    = 1024
+)
+
+var ErrCorruptLog = errors.New("store log is corrupted")
+
+// FileStore is a Store that keeps its data in memory and persists every change to an append-only log in a data directory.
+// A change is written and synced to the log before it is applied, so an acknowledged write survives a crash.
+// A record cut short by a crash is dropped when the log is replayed. The log is rewritten without the overwritten
+// records once it grows too large, through a temporary file which replaces it atomically.
+type FileStore struct {
+	*InMemStore
+
+	mu      sync.Mutex // serializes the writes to the log
+	dir     string
+	file    *os.File
+	size    int64 // length of the complete records in the log
+	records int
+	// failed is set when the log could not be reopened after a compaction, the store then refuses every write
+	failed error
+}
+
+// The records of the log. The users and messages have their own types because
+// their keys are not serialised by the JSON encoding used by the API.
+const (
+	opStoreUser     = "store_user"
+	opSetUser       = "set_user"
+	opStoreMessage  = "store_message"
+	opUpdateMessage = "update_message"
+	opDeleteMessage = "delete_message"
+	opStoreGrant    = "store_grant"
+	opDeleteGrant   = "delete_grant"
+)
+
+type logRecord struct {
+	Op      string         `json:"op"`
+	Key     string         `json:"key,omitempty"` // the key of set_user, the message id of delete_message
+	User    *userRecord    `json:"user,omitempty"`
+	Message *messageRecord `json:"message,omitempty"`
+	Grant   *grantRecord   `json:"grant,omitempty"` // only the message id and the recipient for delete_grant
+}
+
+type userRecord struct {
+	Id            uuid.UUID    `json:"id"`
+	Username      string       `json:"username"`
+	Password      string       `json:"password"`
+	Choice        CipherChoice `json:"choice"`
+	TOTPSecret    string       `json:"totp_secret"`
+	EncryptedKeys []byte       `json:"encrypted_keys"`
+}
+
+type messageRecord struct {
+	Id               uuid.UUID     `json:"id"`
+	EncryptedMessage []byte        `json:"encrypted_message"`
+	EncryptionAlg    EncryptionAlg `json:"encryption_alg"`
+	Author           string        `json:"author"`
+	CreatedAt        time.Time     `json:"created_at"`
+	WrappedKey       []byte        `json:"wrapped_key"`
+}
+
+type grantRecord struct {
+	MessageId  uuid.UUID `json:"message_id"`
+	Recipient  string    `json:"recipient"`
+	CreatedAt  time.Time `json:"created_at"`
+	WrappedKey []byte    `json:"wrapped_key,omitempty"`
+}
+
+func newUserRecord(user User) *userRecord {
+	return &userRecord{
+		Id:            user.Id,
+		Username:      user.Username,
+		Password:      user.Password,
+		Choice:        user.Choice,
+		TOTPSecret:    user.TOTPSecret,
+		EncryptedKeys: user.EncryptedKeys,
+	}
+}
+
+func (r *userRecord) user() User {
+	return User{
+		Id:            r.Id,
+		Username:      r.Username,
+		Password:      r.Password,
+		Choice:        r.Choice,
+		TOTPSecret:    r.TOTPSecret,
+		EncryptedKeys: r.EncryptedKeys,
+	}
+}
+
+func newMessageRecord(message Message) *messageRecord {
+	return &messageRecord{
+		Id:               message.Id,
+		EncryptedMessage: message.EncryptedMessage,
+		EncryptionAlg:    message.EncryptionAlg,
+		Author:           message.Author,
+		CreatedAt:        message.CreatedAt,
+		WrappedKey:       message.WrappedKey,
+	}
+}
+
+func (r *messageRecord) message() Message {
+	return Message{
+		Id:               r.Id,
+		EncryptedMessage: r.EncryptedMessage,
+		EncryptionAlg:    r.EncryptionAlg,
+		Author:           r.Author,
+		CreatedAt:        r.CreatedAt,
+		WrappedKey:       r.WrappedKey,
+	}
+}
+
+func newGrantRecord(grant Grant) *grantRecord {
+	return &grantRecord{
+		MessageId:  grant.MessageId,
+		Recipient:  grant.Recipient,
+		CreatedAt:  grant.CreatedAt,
+		WrappedKey: grant.WrappedKey,
+	}
+}
+
+func (r *grantRecord) grant() Grant {
+	return Grant{
+		MessageId:  r.MessageId,
+		Recipient:  r.Recipient,
+		CreatedAt:  r.CreatedAt,
+		WrappedKey: r.WrappedKey,
+	}
+}
+
+// NewFileStore opens the store kept in dir, creating the directory and the log if needed, and replays the log
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	store := &FileStore{InMemStore: newInMemStore(), dir: dir}
+
+	file, err := os.OpenFile(store.logPath(), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.replay(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	store.file = file
+
+	if store.needsCompaction() {
+		if err := store.Compact(); err != nil {
+			store.Close()
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+func (store *FileStore) logPath() string {
+	return filepath.Join(store.dir, logFileName)
+}
+
+// replay applies every record of the log. A damaged last record is the trace of an interrupted write, it is cut off.
+func (store *FileStore) replay(file *os.File) error {
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// a last line without its newline is a record that was not completely written
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		record, ok := decodeRecord(line)
+		if !ok {
+			// Only the last record can be damaged by a crash, anything after it means the log itself is broken
+			if _, err := reader.Peek(1); err != io.EOF {
+				return fmt.Errorf("%w: bad record at offset %d", ErrCorruptLog, offset)
+			}
+			break
+		}
+		if err := store.apply(record); err != nil {
+			return err
+		}
+		store.records++
+		offset += int64(len(line))
+	}
+
+	store.size = offset
+	return file.Truncate(offset)
+}
+
+// apply changes the in-memory state according to record
+func (store *FileStore) apply(record logRecord) error {
+	switch {
+	case record.Op == opStoreUser && record.User != nil:
+		user := record.User.user()
+		err := store.InMemStore.StoreUser(&user)
+		if err == ErrDuplicateUser {
+			// logs written before StoreUser refused a taken username may hold a user twice, the last record wins
+			return store.InMemStore.SetUser(user.Username, user)
+		}
+		return err
+	case record.Op == opSetUser && record.User != nil:
+		return store.InMemStore.SetUser(record.Key, record.User.user())
+	case record.Op == opStoreMessage && record.Message != nil:
+		message := record.Message.message()
+		return store.InMemStore.StoreMessage(&message)
+	case record.Op == opUpdateMessage && record.Message != nil:
+		message := record.Message.message()
+		return store.InMemStore.UpdateMessage(&message)
+	case record.Op == opDeleteMessage:
+		id, err := uuid.Parse(record.Key)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrCorruptLog, err)
+		}
+		return store.InMemStore.DeleteMessage(id)
+	case record.Op == opStoreGrant && record.Grant != nil:
+		grant := record.Grant.grant()
+		return store.InMemStore.StoreGrant(&grant)
+	case record.Op == opDeleteGrant && record.Grant != nil:
+		return store.InMemStore.DeleteGrant(record.Grant.MessageId, record.Grant.Recipient)
+	}
+	return fmt.Errorf("%w: unknown record %q", ErrCorruptLog, record.Op)
+}
+
+// check returns the error that applying record would return, the writes being serialized by mu
+func (store *FileStore) check(record logRecord) error {
+	switch record.Op {
+	case opStoreUser:
+		if _, err := store.InMemStore.GetUser(record.User.Username); err == nil {
+			return ErrDuplicateUser
+		}
+		return nil
+	case opUpdateMessage:
+		_, err := store.InMemStore.GetMessage(record.Message.Id)
+		return err
+	case opDeleteMessage:
+		id, err := uuid.Parse(record.Key)
+		if err != nil {
+			return err
+		}
+		_, err = store.InMemStore.GetMessage(id)
+		return err
+	case opStoreGrant:
+		_, err := store.InMemStore.GetMessage(record.Grant.MessageId)
+		return err
+	case opDeleteGrant:
+		_, err := store.InMemStore.GetGrant(record.Grant.MessageId, record.Grant.Recipient)
+		return err
+	}
+	return nil
+}
+
+// encodeRecord writes a record as one line: the CRC-32 of the JSON in hexadecimal, a space, the JSON and a newline
+func encodeRecord(record logRecord) ([]byte, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	line := []byte(fmt.Sprintf("%08x ", crc32.ChecksumIEEE(data)))
+	line = append(line, data...)
+	return append(line, '\n'), nil
+}
+
+func decodeRecord(line []byte) (logRecord, bool) {
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	if len(line) < 9 || line[8] != ' ' {
+		return logRecord{}, false
+	}
+	var checksum uint32
+	if _, err := fmt.Sscanf(string(line[:8]), "%08x", &checksum); err != nil {
+		return logRecord{}, false
+	}
+	data := line[9:]
+	if crc32.ChecksumIEEE(data) != checksum {
+		return logRecord{}, false
+	}
+	var record logRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return logRecord{}, false
+	}
+	return record, true
+}
+
+// write appends record to the log and syncs it, then applies it to the in-memory state
+func (store *FileStore) write(record logRecord) error {
+	line, err := encodeRecord(record)
+	if err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if store.failed != nil {
+		return store.failed
+	}
+	if store.file == nil {
+		return os.ErrClosed
+	}
+	// a record that can not be applied must not reach the log, where it would stop the replay
+	if err := store.check(record); err != nil {
+		return err
+	}
+	if _, err := store.file.Write(line); err != nil {
+		// drop what may have been written, the next record must follow a complete one
+		store.file.Truncate(store.size)
+		return err
+	}
+	if err := store.file.Sync(); err != nil {
+		store.file.Truncate(store.size)
+		return err
+	}
+	store.size += int64(len(line))
+	if err := store.apply(record); err != nil {
+		return err
+	}
+	store.records++
+
+	// the record is durable at this point, a failed compaction leaves the log as it was and is tried again on the next write
+	if store.needsCompaction() {
+		if err := store.compact(); err != nil {
+			log.Printf("store: compaction of %s failed: %v", store.logPath(), err)
+		}
+	}
+	return nil
+}
+
+func (store *FileStore) StoreUser(user *User) error {
+	return store.write(logRecord{Op: opStoreUser, User: newUserRecord(*user)})
+}
+
+func (store *FileStore) SetUser(key string, value User) error {
+	return store.write(logRecord{Op: opSetUser, Key: key, User: newUserRecord(value)})
+}
+
+func (store *FileStore) StoreMessage(message *Message) error {
+	return store.write(logRecord{Op: opStoreMessage, Message: newMessageRecord(*message)})
+}
+
+func (store *FileStore) UpdateMessage(message *Message) error {
+	return store.write(logRecord{Op: opUpdateMessage, Message: newMessageRecord(*message)})
+}
+
+func (store *FileStore) DeleteMessage(id uuid.UUID) error {
+	return store.write(logRecord{Op: opDeleteMessage, Key: id.String()})
+}
+
+func (store *FileStore) StoreGrant(grant *Grant) error {
+	return store.write(logRecord{Op: opStoreGrant, Grant: newGrantRecord(*grant)})
+}
+
+func (store *FileStore) DeleteGrant(messageId uuid.UUID, recipient string) error {
+	return store.write(logRecord{Op: opDeleteGrant, Grant: &grantRecord{MessageId: messageId, Recipient: recipient}})
+}
+
+func (store *FileStore) needsCompaction() bool {
+	return store.records > minCompaction && store.records > compactionFactor*store.InMemStore.size()
+}
+
+// Compact rewrites the log with only the current users, messages and grants
+func (store *FileStore) Compact() error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if store.failed != nil {
+		return store.failed
+	}
+	if store.file == nil {
+		return os.ErrClosed
+	}
+	return store.compact()
+}
+
+// compact writes the current state to a temporary file, syncs it and renames it over the log,
+// so that a crash at any point leaves either the old or the new log complete
+func (store *FileStore) compact() error {
+	users, messages, grants := store.InMemStore.snapshot()
+
+	tmpPath := store.logPath() + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+
+	writer := bufio.NewWriter(tmp)
+	records, size := 0, int64(0)
+	writeRecord := func(record logRecord) error {
+		line, err := encodeRecord(record)
+		if err != nil {
+			return err
+		}
+		records++
+		size += int64(len(line))
+		_, err = writer.Write(line)
+		return err
+	}
+
+	for key, user := range users {
+		record := logRecord{Op: opStoreUser, User: newUserRecord(user)}
+		if key != user.Username {
+			record = logRecord{Op: opSetUser, Key: key, User: newUserRecord(user)}
+		}
+		if err := writeRecord(record); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	for _, list := range messages {
+		for _, message := range list {
+			if err := writeRecord(logRecord{Op: opStoreMessage, Message: newMessageRecord(message)}); err != nil {
+				tmp.Close()
+				return err
+			}
+		}
+	}
+	// the grants follow the messages they refer to
+	for _, grant := range grants {
+		if err := writeRecord(logRecord{Op: opStoreGrant, Grant: newGrantRecord(grant)}); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, store.logPath()); err != nil {
+		return err
+	}
+
+	// the old file descriptor points to the replaced log, whatever happens next it must not be written to again
+	syncErr := syncDir(store.dir)
+	file, err := os.OpenFile(store.logPath(), os.O_WRONLY|os.O_APPEND, 0600)
+	store.file.Close()
+	if err != nil {
+		store.file = nil
+		store.failed = fmt.Errorf("store log can not be reopened after its compaction: %w", err)
+		return store.failed
+	}
+	store.file = file
+	store.records = records
+	store.size = size
+	return syncErr
+}
+
+// syncDir makes a rename in dir durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Close closes the log. The store can not be written afterwards.
+func (store *FileStore) Close() error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if store.file == nil {
+		return nil
+	}
+	err := store.file.Close()
+	store.file = nil
+	return err
+}
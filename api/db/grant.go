@@ -0,0 +1,31 @@
+package db
+
+import (
+	"bytes"
+	"github.com/google/uuid"
+	"sort"
+	"time"
+)
+
+// Grant gives Recipient read access to a message of another user
+type Grant struct {
+	MessageId uuid.UUID `json:"message_id"`
+	Recipient string    `json:"recipient"`
+	CreatedAt time.Time `json:"created_at"`
+	// WrappedKey holds what the recipient needs to read the message, sealed for the recipient
+	WrappedKey []byte `json:"-"`
+}
+
+// sortGrants orders grants by creation time, then by message and recipient
+func sortGrants(grants []Grant) {
+	sort.Slice(grants, func(i, j int) bool {
+		a, b := grants[i], grants[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		if a.MessageId != b.MessageId {
+			return bytes.Compare(a.MessageId[:], b.MessageId[:]) < 0
+		}
+		return a.Recipient < b.Recipient
+	})
+}
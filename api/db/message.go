@@ -1,12 +1,19 @@
 package db
 
-import "github.com/google/uuid"
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+)
 
 type Message struct {
 	Id               uuid.UUID     `json:"id"`
 	EncryptedMessage []byte        `json:"encrypted_message"`
 	EncryptionAlg    EncryptionAlg `json:"encryption_alg"`
 	Author           string        `json:"author"`
+	CreatedAt        time.Time     `json:"created_at"`
+	// WrappedKey holds the keys of the message, sealed under the key encryption key of the author
+	WrappedKey []byte `json:"-"`
 }
 
 type EncryptionAlg int
@@ -19,4 +26,28 @@ const (
 	Vigener
 	Blowfish
 	OneTimePad
+	ElGamal
 )
+
+// String returns the name of the algorithm, as given by its cipher
+func (alg EncryptionAlg) String() string {
+	switch alg {
+	case Rsa:
+		return "RSA"
+	case Caesar:
+		return "Caesar Cipher"
+	case CaesarPerm:
+		return "Caesar Cipher with Permutation"
+	case Playfair:
+		return "Playfair cipher"
+	case Vigener:
+		return "Vigenere Cipher"
+	case Blowfish:
+		return "Blowfish"
+	case OneTimePad:
+		return "One Time Pad"
+	case ElGamal:
+		return "ElGamal"
+	}
+	return fmt.Sprintf("EncryptionAlg(%d)", int(alg))
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"bytes"
+	"github.com/google/uuid"
+	"sort"
+	"time"
+)
+
+// MessageQuery selects the messages of an author, ordered by creation time and then by id
+type MessageQuery struct {
+	Author string
+	// Algorithms keeps only the messages encrypted with one of them, all messages are kept when it is empty
+	Algorithms []EncryptionAlg
+	// From and To bound the creation time, From included and To excluded. A zero time is no bound.
+	From time.Time
+	To   time.Time
+	// After keeps only the messages that come after the cursor, to fetch the next page
+	After *MessageCursor
+	// Limit is the maximum number of messages returned, 0 is no limit
+	Limit int
+}
+
+// MessageCursor is the position of a message in the order of MessageQuery
+type MessageCursor struct {
+	CreatedAt time.Time
+	Id        uuid.UUID
+}
+
+// CursorOf returns the position of message
+func CursorOf(message Message) MessageCursor {
+	return MessageCursor{CreatedAt: message.CreatedAt, Id: message.Id}
+}
+
+// less orders the messages by creation time and then by id
+func (c MessageCursor) less(other MessageCursor) bool {
+	if !c.CreatedAt.Equal(other.CreatedAt) {
+		return c.CreatedAt.Before(other.CreatedAt)
+	}
+	return bytes.Compare(c.Id[:], other.Id[:]) < 0
+}
+
+// matches reports whether message is selected by the filters of query, without the limit
+func (query MessageQuery) matches(message Message) bool {
+	if message.Author != query.Author {
+		return false
+	}
+	if len(query.Algorithms) != 0 {
+		found := false
+		for _, alg := range query.Algorithms {
+			if alg == message.EncryptionAlg {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !query.From.IsZero() && message.CreatedAt.Before(query.From) {
+		return false
+	}
+	if !query.To.IsZero() && !message.CreatedAt.Before(query.To) {
+		return false
+	}
+	if query.After != nil && !query.After.less(CursorOf(message)) {
+		return false
+	}
+	return true
+}
+
+// filterMessages applies query to messages
+func filterMessages(messages []Message, query MessageQuery) []Message {
+	selected := []Message{}
+	for _, message := range messages {
+		if query.matches(message) {
+			selected = append(selected, message)
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return CursorOf(selected[i]).less(CursorOf(selected[j]))
+	})
+	if query.Limit > 0 && len(selected) > query.Limit {
+		selected = selected[:query.Limit]
+	}
+	return selected
+}
@@ -0,0 +1,421 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const sqliteFileName = "store.db"
+
+// migrations are the versions of the schema, applied in order. A migration must never change once released,
+// a new version is added instead.
+var migrations = []string{
+	// 1: users and messages
+	`CREATE TABLE users (
+		key            TEXT PRIMARY KEY,
+		id             TEXT NOT NULL,
+		username       TEXT NOT NULL,
+		password       TEXT NOT NULL,
+		choice         INTEGER NOT NULL,
+		totp_secret    TEXT NOT NULL,
+		encrypted_keys BLOB
+	);
+	CREATE INDEX users_username ON users (username);
+	CREATE INDEX users_id ON users (id);
+	CREATE TABLE messages (
+		seq               INTEGER PRIMARY KEY AUTOINCREMENT,
+		id                TEXT NOT NULL UNIQUE,
+		author            TEXT NOT NULL,
+		encryption_alg    INTEGER NOT NULL,
+		encrypted_message BLOB NOT NULL,
+		wrapped_key       BLOB
+	);
+	CREATE INDEX messages_author ON messages (author, seq);`,
+	// 2: creation time of the messages, in nanoseconds since the epoch, 0 for the messages stored before
+	`ALTER TABLE messages ADD COLUMN created_at INTEGER NOT NULL DEFAULT 0;
+	CREATE INDEX messages_author_created ON messages (author, created_at, id);`,
+	// 3: grants of read access on a message
+	`CREATE TABLE grants (
+		message_id  TEXT NOT NULL,
+		recipient   TEXT NOT NULL,
+		created_at  INTEGER NOT NULL,
+		wrapped_key BLOB,
+		PRIMARY KEY (message_id, recipient)
+	);
+	CREATE INDEX grants_recipient ON grants (recipient, created_at);`,
+}
+
+// SQLStore is a Store kept in a SQLite database, through a pure Go driver.
+// Every write runs in a transaction, so a user or a message is stored completely or not at all.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore opens the SQLite database of the data directory dir, creating it if needed, and migrates its schema
+func NewSQLStore(dir string) (*SQLStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	dsn := "file:" + filepath.Join(dir, sqliteFileName) + "?" + url.Values{
+		"_pragma": {"journal_mode(WAL)", "synchronous(FULL)", "busy_timeout(5000)"},
+		"_txlock": {"immediate"},
+	}.Encode()
+
+	database, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	store := &SQLStore{db: database}
+	if err := store.migrate(); err != nil {
+		database.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// migrate applies the migrations newer than the version of the database, each one in its own transaction
+func (store *SQLStore) migrate() error {
+	_, err := store.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+
+	var version int
+	if err := store.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than this program (%d)", version, len(migrations))
+	}
+
+	for v := version + 1; v <= len(migrations); v++ {
+		err := store.withTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(migrations[v-1]); err != nil {
+				return fmt.Errorf("migration %d: %w", v, err)
+			}
+			_, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, v, time.Now().UTC().Format(time.RFC3339))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withTx runs f in a transaction, committed if f succeeds and rolled back otherwise
+func (store *SQLStore) withTx(f func(tx *sql.Tx) error) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+const upsertUser = `INSERT INTO users (key, id, username, password, choice, totp_secret, encrypted_keys)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (key) DO UPDATE SET id = excluded.id, username = excluded.username, password = excluded.password,
+		choice = excluded.choice, totp_secret = excluded.totp_secret, encrypted_keys = excluded.encrypted_keys`
+
+func execUpsertUser(tx *sql.Tx, key string, user User) error {
+	_, err := tx.Exec(upsertUser, key, user.Id.String(), user.Username, user.Password, int(user.Choice), user.TOTPSecret, user.EncryptedKeys)
+	return err
+}
+
+// StoreUser inserts a new user, the primary key on the username making a second insertion fail with ErrDuplicateUser
+func (store *SQLStore) StoreUser(user *User) error {
+	return store.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`INSERT INTO users (key, id, username, password, choice, totp_secret, encrypted_keys) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			user.Username, user.Id.String(), user.Username, user.Password, int(user.Choice), user.TOTPSecret, user.EncryptedKeys)
+		if isConstraintError(err, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY) {
+			return ErrDuplicateUser
+		}
+		return err
+	})
+}
+
+// isConstraintError tells whether err is the violation of a constraint of the given extended result code
+func isConstraintError(err error, code int) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == code
+}
+
+func (store *SQLStore) SetUser(key string, value User) error {
+	return store.withTx(func(tx *sql.Tx) error {
+		return execUpsertUser(tx, key, value)
+	})
+}
+
+func (store *SQLStore) GetUser(key string) (User, error) {
+	row := store.db.QueryRow(`SELECT id, username, password, choice, totp_secret, encrypted_keys FROM users WHERE key = ?`, key)
+
+	var user User
+	var id string
+	err := row.Scan(&id, &user.Username, &user.Password, &user.Choice, &user.TOTPSecret, &user.EncryptedKeys)
+	if err == sql.ErrNoRows {
+		return User{}, fmt.Errorf("%w with key %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return User{}, err
+	}
+	if user.Id, err = uuid.Parse(id); err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+func (store *SQLStore) StoreMessage(message *Message) error {
+	return store.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`INSERT INTO messages (id, author, encryption_alg, encrypted_message, wrapped_key, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			message.Id.String(), message.Author, int(message.EncryptionAlg), message.EncryptedMessage, message.WrappedKey, sqlTime(message.CreatedAt))
+		return err
+	})
+}
+
+// UpdateMessage replaces the stored message with the same id, keeping its place among the messages of its author
+func (store *SQLStore) UpdateMessage(message *Message) error {
+	return store.withTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`UPDATE messages SET author = ?, encryption_alg = ?, encrypted_message = ?, wrapped_key = ?, created_at = ? WHERE id = ?`,
+			message.Author, int(message.EncryptionAlg), message.EncryptedMessage, message.WrappedKey, sqlTime(message.CreatedAt), message.Id.String())
+		if err != nil {
+			return err
+		}
+		return checkAffected(result, message.Id)
+	})
+}
+
+// DeleteMessage removes the message with the given id and its grants
+func (store *SQLStore) DeleteMessage(id uuid.UUID) error {
+	return store.withTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`DELETE FROM messages WHERE id = ?`, id.String())
+		if err != nil {
+			return err
+		}
+		if err := checkAffected(result, id); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`DELETE FROM grants WHERE message_id = ?`, id.String())
+		return err
+	})
+}
+
+// checkAffected returns an error if the statement did not change the row of id
+func checkAffected(result sql.Result, id any) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("%w with key %s", ErrNotFound, id)
+	}
+	return nil
+}
+
+const selectMessage = `SELECT id, author, encryption_alg, encrypted_message, wrapped_key, created_at FROM messages`
+
+// sqlTime is the column value of t, the zero time being stored as 0
+func sqlTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func timeFromSQL(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos).UTC()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMessage(row rowScanner) (Message, error) {
+	var message Message
+	var id string
+	var createdAt int64
+	if err := row.Scan(&id, &message.Author, &message.EncryptionAlg, &message.EncryptedMessage, &message.WrappedKey, &createdAt); err != nil {
+		return Message{}, err
+	}
+	message.CreatedAt = timeFromSQL(createdAt)
+	var err error
+	message.Id, err = uuid.Parse(id)
+	return message, err
+}
+
+func (store *SQLStore) GetMessage(id uuid.UUID) (Message, error) {
+	message, err := scanMessage(store.db.QueryRow(selectMessage+` WHERE id = ?`, id.String()))
+	if err == sql.ErrNoRows {
+		return Message{}, fmt.Errorf("%w with key %s", ErrNotFound, id)
+	}
+	return message, err
+}
+
+func (store *SQLStore) GetMessagesOfUser(username string) ([]Message, error) {
+	messages, err := store.queryMessages(selectMessage+` WHERE author = ? ORDER BY seq`, username)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("%w with key %s", ErrNotFound, username)
+	}
+	return messages, nil
+}
+
+// ListMessages returns the messages selected by query, using the index on the author and the creation time
+func (store *SQLStore) ListMessages(query MessageQuery) ([]Message, error) {
+	where := ` WHERE author = ?`
+	args := []any{query.Author}
+	if len(query.Algorithms) != 0 {
+		where += ` AND encryption_alg IN (?` + strings.Repeat(`, ?`, len(query.Algorithms)-1) + `)`
+		for _, alg := range query.Algorithms {
+			args = append(args, int(alg))
+		}
+	}
+	if !query.From.IsZero() {
+		where += ` AND created_at >= ?`
+		args = append(args, sqlTime(query.From))
+	}
+	if !query.To.IsZero() {
+		where += ` AND created_at < ?`
+		args = append(args, sqlTime(query.To))
+	}
+	if query.After != nil {
+		createdAt := sqlTime(query.After.CreatedAt)
+		where += ` AND (created_at > ? OR (created_at = ? AND id > ?))`
+		args = append(args, createdAt, createdAt, query.After.Id.String())
+	}
+	where += ` ORDER BY created_at, id`
+	if query.Limit > 0 {
+		where += ` LIMIT ?`
+		args = append(args, query.Limit)
+	}
+
+	return store.queryMessages(selectMessage+where, args...)
+}
+
+func (store *SQLStore) queryMessages(query string, args ...any) ([]Message, error) {
+	rows, err := store.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []Message{}
+	for rows.Next() {
+		message, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// StoreGrant stores grant, replacing the grant of the same message to the same recipient
+func (store *SQLStore) StoreGrant(grant *Grant) error {
+	return store.withTx(func(tx *sql.Tx) error {
+		var exists int
+		err := tx.QueryRow(`SELECT 1 FROM messages WHERE id = ?`, grant.MessageId.String()).Scan(&exists)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w with key %s", ErrNotFound, grant.MessageId)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO grants (message_id, recipient, created_at, wrapped_key) VALUES (?, ?, ?, ?)
+			ON CONFLICT (message_id, recipient) DO UPDATE SET created_at = excluded.created_at, wrapped_key = excluded.wrapped_key`,
+			grant.MessageId.String(), grant.Recipient, sqlTime(grant.CreatedAt), grant.WrappedKey)
+		return err
+	})
+}
+
+const selectGrant = `SELECT message_id, recipient, created_at, wrapped_key FROM grants`
+
+func scanGrant(row rowScanner) (Grant, error) {
+	var grant Grant
+	var messageId string
+	var createdAt int64
+	if err := row.Scan(&messageId, &grant.Recipient, &createdAt, &grant.WrappedKey); err != nil {
+		return Grant{}, err
+	}
+	grant.CreatedAt = timeFromSQL(createdAt)
+	var err error
+	grant.MessageId, err = uuid.Parse(messageId)
+	return grant, err
+}
+
+func (store *SQLStore) GetGrant(messageId uuid.UUID, recipient string) (Grant, error) {
+	grant, err := scanGrant(store.db.QueryRow(selectGrant+` WHERE message_id = ? AND recipient = ?`, messageId.String(), recipient))
+	if err == sql.ErrNoRows {
+		return Grant{}, fmt.Errorf("%w with key %s/%s", ErrNotFound, messageId, recipient)
+	}
+	return grant, err
+}
+
+// GetGrantsOfMessage returns the grants of a message, oldest first
+func (store *SQLStore) GetGrantsOfMessage(messageId uuid.UUID) ([]Grant, error) {
+	return store.queryGrants(selectGrant+` WHERE message_id = ? ORDER BY created_at, recipient`, messageId.String())
+}
+
+// GetGrantsOfRecipient returns the grants given to recipient, oldest first
+func (store *SQLStore) GetGrantsOfRecipient(recipient string) ([]Grant, error) {
+	return store.queryGrants(selectGrant+` WHERE recipient = ? ORDER BY created_at, message_id`, recipient)
+}
+
+func (store *SQLStore) queryGrants(query string, args ...any) ([]Grant, error) {
+	rows, err := store.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := []Grant{}
+	for rows.Next() {
+		grant, err := scanGrant(rows)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, grant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+func (store *SQLStore) DeleteGrant(messageId uuid.UUID, recipient string) error {
+	return store.withTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`DELETE FROM grants WHERE message_id = ? AND recipient = ?`, messageId.String(), recipient)
+		if err != nil {
+			return err
+		}
+		return checkAffected(result, messageId.String()+"/"+recipient)
+	})
+}
+
+// Close closes the database
+func (store *SQLStore) Close() error {
+	return store.db.Close()
+}
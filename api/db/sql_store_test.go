@@ -0,0 +1,102 @@
+package db
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func schemaVersion(t *testing.T, store *SQLStore) int {
+	t.Helper()
+	var version, count int
+	err := store.db.QueryRow(`SELECT COALESCE(MAX(version), 0), COUNT(*) FROM schema_migrations`).Scan(&version, &count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != version {
+		t.Fatalf("%d migrations recorded for version %d", count, version)
+	}
+	return version
+}
+
+func TestMigrations(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewSQLStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version := schemaVersion(t, store); version != len(migrations) {
+		t.Fatalf("a new database is at version %d, want %d", version, len(migrations))
+	}
+	user := User{Username: "alice"}
+	if err := store.StoreUser(&user); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	// opening the database again applies nothing and keeps the data
+	store, err = NewSQLStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version := schemaVersion(t, store); version != len(migrations) {
+		t.Fatalf("a reopened database is at version %d, want %d", version, len(migrations))
+	}
+	if _, err := store.GetUser("alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a database migrated by a newer program is refused rather than used with a schema this one does not know
+	if _, err := store.db.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, '')`, len(migrations)+1); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+	if store, err := NewSQLStore(dir); err == nil {
+		store.Close()
+		t.Fatal("a database with a newer schema version was opened")
+	}
+}
+
+// TestMigrationFromFirstVersion opens a database left at the first version, with a message, and checks that
+// the later migrations are applied on top of its data
+func TestMigrationFromFirstVersion(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewSQLStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	// rebuild the database at version 1
+	database, err := sql.Open("sqlite", "file:"+filepath.Join(dir, sqliteFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, statement := range []string{
+		`DROP TABLE grants`, `DROP TABLE messages`, `DROP TABLE users`, `DELETE FROM schema_migrations`,
+		migrations[0],
+		`INSERT INTO schema_migrations (version, applied_at) VALUES (1, '')`,
+		`INSERT INTO messages (id, author, encryption_alg, encrypted_message) VALUES ('6f3e1c1e-7d4c-4f39-9a55-1b8a0c2d3e4f', 'alice', 5, x'01')`,
+	} {
+		if _, err := database.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	database.Close()
+
+	store, err = NewSQLStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if version := schemaVersion(t, store); version != len(migrations) {
+		t.Fatalf("the database was migrated to version %d, want %d", version, len(migrations))
+	}
+	messages, err := store.GetMessagesOfUser("alice")
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("GetMessagesOfUser after the migration = %v, %v", messages, err)
+	}
+	if !messages[0].CreatedAt.IsZero() {
+		t.Fatalf("a message stored before version 2 has the creation time %v", messages[0].CreatedAt)
+	}
+}
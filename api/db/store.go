@@ -1,31 +1,60 @@
 package db
 
 import (
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	"sync"
+)
+
+var (
+	// ErrNotFound is wrapped by the errors of the lookups, updates and deletions of a missing user, message or grant
+	ErrNotFound = errors.New("No such value present")
+	// ErrDuplicateUser is returned by StoreUser when a user with the same username is already stored
+	ErrDuplicateUser = errors.New("a user with this username already exists")
 )
 
 type Store interface {
-	StoreMessage(message *Message)
-	StoreUser(user *User)
+	StoreMessage(message *Message) error
+	StoreUser(user *User) error
 	GetUser(key string) (User, error)
 	SetUser(key string, value User) error
 	GetMessage(id uuid.UUID) (Message, error)
 	GetMessagesOfUser(username string) ([]Message, error)
+	ListMessages(query MessageQuery) ([]Message, error)
+	UpdateMessage(message *Message) error
+	DeleteMessage(id uuid.UUID) error
+	StoreGrant(grant *Grant) error
+	GetGrant(messageId uuid.UUID, recipient string) (Grant, error)
+	GetGrantsOfMessage(messageId uuid.UUID) ([]Grant, error)
+	GetGrantsOfRecipient(recipient string) ([]Grant, error)
+	DeleteGrant(messageId uuid.UUID, recipient string) error
 }
 
+// InMemStore keeps the users, the messages and their grants in maps. It is safe for concurrent use:
+// the user maps and the message maps have their own lock, so that users and messages do not wait for each other.
 type InMemStore struct {
-	UserById           map[uuid.UUID]*User
-	UserByUsername     map[string]User
+	usersMu        sync.RWMutex
+	UserById       map[uuid.UUID]*User
+	UserByUsername map[string]User
+
+	messagesMu         sync.RWMutex
 	MessageById        map[uuid.UUID]*Message
 	MessagesByUsername map[string][]Message
+	// the grants go away with their message, so they share its lock
+	GrantsByMessage   map[uuid.UUID]map[string]Grant
+	GrantsByRecipient map[string]map[uuid.UUID]Grant
+	grants            int
 }
 
 func (store *InMemStore) GetUser(key string) (User, error) {
+	store.usersMu.RLock()
+	defer store.usersMu.RUnlock()
+
 	value, ok := store.UserByUsername[key]
 
 	if !ok {
-		err := fmt.Errorf("No such value present with key %s", key)
+		err := fmt.Errorf("%w with key %s", ErrNotFound, key)
 		return User{}, err
 	}
 
@@ -33,43 +62,273 @@ func (store *InMemStore) GetUser(key string) (User, error) {
 }
 
 func (store *InMemStore) SetUser(key string, value User) error {
+	store.usersMu.Lock()
+	defer store.usersMu.Unlock()
+
 	store.UserByUsername[key] = value
 	return nil
 }
 
-func (store *InMemStore) StoreMessage(message *Message) {
+func (store *InMemStore) StoreMessage(message *Message) error {
+	store.messagesMu.Lock()
+	defer store.messagesMu.Unlock()
+
 	store.MessageById[message.Id] = message
 	store.MessagesByUsername[message.Author] = append(store.MessagesByUsername[message.Author], *message)
+	return nil
 }
 
-func (store *InMemStore) StoreUser(user *User) {
+// StoreUser stores a new user, failing with ErrDuplicateUser if the username is taken.
+// The check and the insertion happen under the same lock, so two registrations of a username can not both succeed.
+func (store *InMemStore) StoreUser(user *User) error {
+	store.usersMu.Lock()
+	defer store.usersMu.Unlock()
+
+	if _, ok := store.UserByUsername[user.Username]; ok {
+		return ErrDuplicateUser
+	}
 	store.UserById[user.Id] = user
-	_ = store.SetUser(user.Username, *user)
+	store.UserByUsername[user.Username] = *user
+	return nil
 }
 
 func (store *InMemStore) GetMessage(id uuid.UUID) (Message, error) {
+	store.messagesMu.RLock()
+	defer store.messagesMu.RUnlock()
+
 	message, ok := store.MessageById[id]
 	if !ok {
-		err := fmt.Errorf("No such value present with key %s", id)
+		err := fmt.Errorf("%w with key %s", ErrNotFound, id)
 		return Message{}, err
 	}
 	return *message, nil
 }
 
 func (store *InMemStore) GetMessagesOfUser(username string) ([]Message, error) {
+	store.messagesMu.RLock()
+	defer store.messagesMu.RUnlock()
+
 	message, ok := store.MessagesByUsername[username]
 	if !ok {
-		err := fmt.Errorf("No such value present with key %s", username)
+		err := fmt.Errorf("%w with key %s", ErrNotFound, username)
 		return nil, err
 	}
-	return message, nil
+	// the caller gets its own copy, later messages are appended to the stored slice
+	return append([]Message(nil), message...), nil
+}
+
+// ListMessages returns the messages selected by query
+func (store *InMemStore) ListMessages(query MessageQuery) ([]Message, error) {
+	store.messagesMu.RLock()
+	defer store.messagesMu.RUnlock()
+
+	return filterMessages(store.MessagesByUsername[query.Author], query), nil
+}
+
+// UpdateMessage replaces the stored message with the same id, keeping its place among the messages of its author
+func (store *InMemStore) UpdateMessage(message *Message) error {
+	store.messagesMu.Lock()
+	defer store.messagesMu.Unlock()
+
+	old, ok := store.MessageById[message.Id]
+	if !ok {
+		return fmt.Errorf("%w with key %s", ErrNotFound, message.Id)
+	}
+	updated := *message
+	list := store.MessagesByUsername[old.Author]
+	for i := range list {
+		if list[i].Id == message.Id {
+			// the slice may be shared with a caller of GetMessagesOfUser, so it is copied before being changed
+			list = append([]Message(nil), list...)
+			list[i] = updated
+			store.MessagesByUsername[old.Author] = list
+			break
+		}
+	}
+	store.MessageById[message.Id] = &updated
+	return nil
+}
+
+// DeleteMessage removes the message with the given id
+func (store *InMemStore) DeleteMessage(id uuid.UUID) error {
+	store.messagesMu.Lock()
+	defer store.messagesMu.Unlock()
+
+	message, ok := store.MessageById[id]
+	if !ok {
+		return fmt.Errorf("%w with key %s", ErrNotFound, id)
+	}
+	delete(store.MessageById, id)
+	for recipient := range store.GrantsByMessage[id] {
+		store.deleteGrant(id, recipient)
+	}
+
+	list := store.MessagesByUsername[message.Author]
+	remaining := make([]Message, 0, len(list))
+	for _, m := range list {
+		if m.Id != id {
+			remaining = append(remaining, m)
+		}
+	}
+	if len(remaining) == 0 {
+		delete(store.MessagesByUsername, message.Author)
+	} else {
+		store.MessagesByUsername[message.Author] = remaining
+	}
+	return nil
+}
+
+// StoreGrant stores grant, replacing the grant of the same message to the same recipient
+func (store *InMemStore) StoreGrant(grant *Grant) error {
+	store.messagesMu.Lock()
+	defer store.messagesMu.Unlock()
+
+	if _, ok := store.MessageById[grant.MessageId]; !ok {
+		return fmt.Errorf("%w with key %s", ErrNotFound, grant.MessageId)
+	}
+	if _, ok := store.GrantsByMessage[grant.MessageId][grant.Recipient]; !ok {
+		store.grants++
+	}
+	if store.GrantsByMessage[grant.MessageId] == nil {
+		store.GrantsByMessage[grant.MessageId] = map[string]Grant{}
+	}
+	if store.GrantsByRecipient[grant.Recipient] == nil {
+		store.GrantsByRecipient[grant.Recipient] = map[uuid.UUID]Grant{}
+	}
+	store.GrantsByMessage[grant.MessageId][grant.Recipient] = *grant
+	store.GrantsByRecipient[grant.Recipient][grant.MessageId] = *grant
+	return nil
+}
+
+func (store *InMemStore) GetGrant(messageId uuid.UUID, recipient string) (Grant, error) {
+	store.messagesMu.RLock()
+	defer store.messagesMu.RUnlock()
+
+	grant, ok := store.GrantsByMessage[messageId][recipient]
+	if !ok {
+		return Grant{}, fmt.Errorf("%w with key %s/%s", ErrNotFound, messageId, recipient)
+	}
+	return grant, nil
+}
+
+// GetGrantsOfMessage returns the grants of a message, oldest first
+func (store *InMemStore) GetGrantsOfMessage(messageId uuid.UUID) ([]Grant, error) {
+	store.messagesMu.RLock()
+	defer store.messagesMu.RUnlock()
+
+	grants := []Grant{}
+	for _, grant := range store.GrantsByMessage[messageId] {
+		grants = append(grants, grant)
+	}
+	sortGrants(grants)
+	return grants, nil
+}
+
+// GetGrantsOfRecipient returns the grants given to recipient, oldest first
+func (store *InMemStore) GetGrantsOfRecipient(recipient string) ([]Grant, error) {
+	store.messagesMu.RLock()
+	defer store.messagesMu.RUnlock()
+
+	grants := []Grant{}
+	for _, grant := range store.GrantsByRecipient[recipient] {
+		grants = append(grants, grant)
+	}
+	sortGrants(grants)
+	return grants, nil
+}
+
+func (store *InMemStore) DeleteGrant(messageId uuid.UUID, recipient string) error {
+	store.messagesMu.Lock()
+	defer store.messagesMu.Unlock()
+
+	if _, ok := store.GrantsByMessage[messageId][recipient]; !ok {
+		return fmt.Errorf("%w with key %s/%s", ErrNotFound, messageId, recipient)
+	}
+	store.deleteGrant(messageId, recipient)
+	return nil
+}
+
+// deleteGrant removes a grant from both maps, messagesMu must be held
+func (store *InMemStore) deleteGrant(messageId uuid.UUID, recipient string) {
+	delete(store.GrantsByMessage[messageId], recipient)
+	if len(store.GrantsByMessage[messageId]) == 0 {
+		delete(store.GrantsByMessage, messageId)
+	}
+	delete(store.GrantsByRecipient[recipient], messageId)
+	if len(store.GrantsByRecipient[recipient]) == 0 {
+		delete(store.GrantsByRecipient, recipient)
+	}
+	store.grants--
+}
+
+// snapshot returns every user under the keys they are stored with, the messages of every author in the order they were stored
+// and every grant
+func (store *InMemStore) snapshot() (map[string]User, map[string][]Message, []Grant) {
+	store.usersMu.RLock()
+	users := make(map[string]User, len(store.UserByUsername))
+	for key, user := range store.UserByUsername {
+		users[key] = user
+	}
+	store.usersMu.RUnlock()
+
+	store.messagesMu.RLock()
+	messages := make(map[string][]Message, len(store.MessagesByUsername))
+	for author, list := range store.MessagesByUsername {
+		messages[author] = append([]Message(nil), list...)
+	}
+	grants := make([]Grant, 0, store.grants)
+	for _, byRecipient := range store.GrantsByMessage {
+		for _, grant := range byRecipient {
+			grants = append(grants, grant)
+		}
+	}
+	store.messagesMu.RUnlock()
+
+	return users, messages, grants
+}
+
+// size returns the number of users, messages and grants
+func (store *InMemStore) size() int {
+	store.usersMu.RLock()
+	users := len(store.UserByUsername)
+	store.usersMu.RUnlock()
+
+	store.messagesMu.RLock()
+	defer store.messagesMu.RUnlock()
+	return users + len(store.MessageById) + store.grants
+}
+
+// The backends of OpenStore
+const (
+	MemoryBackend = "memory"
+	FileBackend   = "file"
+	SQLiteBackend = "sqlite"
+)
+
+// OpenStore returns the store of backend. The file and SQLite backends keep their data in dataDir.
+func OpenStore(backend string, dataDir string) (Store, error) {
+	switch backend {
+	case MemoryBackend, "":
+		return NewStore(), nil
+	case FileBackend:
+		return NewFileStore(dataDir)
+	case SQLiteBackend:
+		return NewSQLStore(dataDir)
+	}
+	return nil, fmt.Errorf("Unknown store backend %q, want %q, %q or %q", backend, MemoryBackend, FileBackend, SQLiteBackend)
 }
 
 func NewStore() Store {
+	return newInMemStore()
+}
+
+func newInMemStore() *InMemStore {
 	return &InMemStore{
 		UserById:           map[uuid.UUID]*User{},
 		UserByUsername:     map[string]User{},
 		MessageById:        map[uuid.UUID]*Message{},
 		MessagesByUsername: map[string][]Message{},
+		GrantsByMessage:    map[uuid.UUID]map[string]Grant{},
+		GrantsByRecipient:  map[string]map[uuid.UUID]Grant{},
 	}
 }
@@ -0,0 +1,424 @@
+package db_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/EliriaT/CS-Labs/api/db"
+	"github.com/google/uuid"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// backend opens a store in dir. A persistent backend gives back what was written when dir is opened again.
+type backend struct {
+	name       string
+	persistent bool
+	open       func(dir string) (db.Store, error)
+}
+
+var backends = []backend{
+	{"memory", false, func(dir string) (db.Store, error) { return db.NewStore(), nil }},
+	{"file", true, func(dir string) (db.Store, error) { return db.NewFileStore(dir) }},
+	{"sqlite", true, func(dir string) (db.Store, error) { return db.NewSQLStore(dir) }},
+}
+
+// openStore opens the store of b in dir, closed at the end of the test
+func openStore(t *testing.T, b backend, dir string) db.Store {
+	t.Helper()
+	store, err := b.open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { closeStore(store) })
+	return store
+}
+
+func closeStore(store db.Store) {
+	if closer, ok := store.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+// forEachBackend runs test as a subtest for every backend, with a fresh store and its data directory
+func forEachBackend(t *testing.T, test func(t *testing.T, b backend, store db.Store, dir string)) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			dir := t.TempDir()
+			test(t, b, openStore(t, b, dir), dir)
+		})
+	}
+}
+
+func newUser(username string) db.User {
+	return db.User{
+		Id:            uuid.New(),
+		Username:      username,
+		Password:      "hash of " + username,
+		Choice:        db.SymmetricUser,
+		TOTPSecret:    "SECRET",
+		EncryptedKeys: []byte("keys of " + username),
+	}
+}
+
+// createdAt is the creation time of the i-th test message, in UTC and to the nanosecond as the stores keep it
+func createdAt(i int) time.Time {
+	return time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC).Add(time.Duration(i) * time.Second)
+}
+
+func newMessage(author string, i int) db.Message {
+	return db.Message{
+		Id:               uuid.New(),
+		EncryptedMessage: []byte{byte(i), 1, 2, 3},
+		EncryptionAlg:    db.Blowfish,
+		Author:           author,
+		CreatedAt:        createdAt(i),
+		WrappedKey:       []byte{4, 5, 6, byte(i)},
+	}
+}
+
+func checkUser(t *testing.T, store db.Store, key string, want db.User) {
+	t.Helper()
+	got, err := store.GetUser(key)
+	if err != nil {
+		t.Fatalf("GetUser(%q): %v", key, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetUser(%q) = %+v, want %+v", key, got, want)
+	}
+}
+
+func sameMessage(a, b db.Message) bool {
+	return a.Id == b.Id && bytes.Equal(a.EncryptedMessage, b.EncryptedMessage) && a.EncryptionAlg == b.EncryptionAlg &&
+		a.Author == b.Author && a.CreatedAt.Equal(b.CreatedAt) && bytes.Equal(a.WrappedKey, b.WrappedKey)
+}
+
+func checkMessages(t *testing.T, store db.Store, author string, want []db.Message) {
+	t.Helper()
+	got, err := store.GetMessagesOfUser(author)
+	if err != nil {
+		t.Fatalf("GetMessagesOfUser(%q): %v", author, err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("GetMessagesOfUser(%q) returned %d messages, want %d", author, len(got), len(want))
+	}
+	for i := range want {
+		if !sameMessage(got[i], want[i]) {
+			t.Fatalf("message %d of %q = %+v, want %+v", i, author, got[i], want[i])
+		}
+	}
+}
+
+func TestOpenStore(t *testing.T) {
+	for _, backend := range []string{db.MemoryBackend, db.FileBackend, db.SQLiteBackend} {
+		store, err := db.OpenStore(backend, t.TempDir())
+		if err != nil {
+			t.Fatalf("OpenStore(%q): %v", backend, err)
+		}
+		closeStore(store)
+	}
+	if _, err := db.OpenStore("postgres", t.TempDir()); err == nil {
+		t.Fatal("an unknown backend was opened")
+	}
+}
+
+func TestUsers(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend, store db.Store, dir string) {
+		alice := newUser("alice")
+		if err := store.StoreUser(&alice); err != nil {
+			t.Fatal(err)
+		}
+		checkUser(t, store, "alice", alice)
+
+		again := newUser("alice")
+		if err := store.StoreUser(&again); err != db.ErrDuplicateUser {
+			t.Fatalf("StoreUser of a taken username = %v, want ErrDuplicateUser", err)
+		}
+		checkUser(t, store, "alice", alice)
+
+		alice.Password = "new hash"
+		if err := store.SetUser("alice", alice); err != nil {
+			t.Fatal(err)
+		}
+		checkUser(t, store, "alice", alice)
+
+		// SetUser stores under any key, which GetUser then reads
+		if err := store.SetUser("alias", alice); err != nil {
+			t.Fatal(err)
+		}
+		checkUser(t, store, "alias", alice)
+
+		if _, err := store.GetUser("bob"); !errors.Is(err, db.ErrNotFound) {
+			t.Fatalf("GetUser of a missing user = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestMessages(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend, store db.Store, dir string) {
+		var alice, bob []db.Message
+		for i := 0; i < 5; i++ {
+			message := newMessage("alice", i)
+			if err := store.StoreMessage(&message); err != nil {
+				t.Fatal(err)
+			}
+			alice = append(alice, message)
+			if i%2 == 0 {
+				message := newMessage("bob", i)
+				if err := store.StoreMessage(&message); err != nil {
+					t.Fatal(err)
+				}
+				bob = append(bob, message)
+			}
+		}
+
+		for _, want := range append(append([]db.Message(nil), alice...), bob...) {
+			got, err := store.GetMessage(want.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sameMessage(got, want) {
+				t.Fatalf("GetMessage = %+v, want %+v", got, want)
+			}
+		}
+		checkMessages(t, store, "alice", alice)
+		checkMessages(t, store, "bob", bob)
+
+		missing := newMessage("alice", 9)
+		if _, err := store.GetMessage(missing.Id); !errors.Is(err, db.ErrNotFound) {
+			t.Fatalf("GetMessage of a missing message = %v, want ErrNotFound", err)
+		}
+		if err := store.UpdateMessage(&missing); !errors.Is(err, db.ErrNotFound) {
+			t.Fatalf("UpdateMessage of a missing message = %v, want ErrNotFound", err)
+		}
+		if err := store.DeleteMessage(missing.Id); !errors.Is(err, db.ErrNotFound) {
+			t.Fatalf("DeleteMessage of a missing message = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestReopen(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend, store db.Store, dir string) {
+		if !b.persistent {
+			t.Skip("the store does not persist its data")
+		}
+
+		alice := newUser("alice")
+		if err := store.StoreUser(&alice); err != nil {
+			t.Fatal(err)
+		}
+		alice.TOTPSecret = "NEW SECRET"
+		if err := store.SetUser("alice", alice); err != nil {
+			t.Fatal(err)
+		}
+		var messages []db.Message
+		for i := 0; i < 3; i++ {
+			message := newMessage("alice", i)
+			if err := store.StoreMessage(&message); err != nil {
+				t.Fatal(err)
+			}
+			messages = append(messages, message)
+		}
+		closeStore(store)
+
+		reopened := openStore(t, b, dir)
+		checkUser(t, reopened, "alice", alice)
+		checkMessages(t, reopened, "alice", messages)
+	})
+}
+
+// TestTornTail appends what a crash in the middle of a write leaves at the end of the log,
+// which must be dropped on replay without losing the records before it
+func TestTornTail(t *testing.T) {
+	tails := map[string]string{
+		"no newline":   `0badc0de {"op":"store_message","message":{"id":`,
+		"bad checksum": `00000000 {"op":"store_user","user":{"username":"mallory"}}` + "\n",
+	}
+
+	for name, tail := range tails {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			store, err := db.NewFileStore(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			alice := newUser("alice")
+			message := newMessage("alice", 0)
+			if err := store.StoreUser(&alice); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.StoreMessage(&message); err != nil {
+				t.Fatal(err)
+			}
+			store.Close()
+
+			logPath := filepath.Join(dir, "store.log")
+			complete, err := os.ReadFile(logPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(logPath, append(append([]byte(nil), complete...), tail...), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			store, err = db.NewFileStore(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkUser(t, store, "alice", alice)
+			checkMessages(t, store, "alice", []db.Message{message})
+			if _, err := store.GetUser("mallory"); err == nil {
+				t.Fatal("the damaged record was applied")
+			}
+
+			// the tail is truncated, so the next record follows the last complete one
+			if data, _ := os.ReadFile(logPath); !bytes.Equal(data, complete) {
+				t.Fatalf("the log was not truncated to its complete records")
+			}
+			second := newMessage("alice", 1)
+			if err := store.StoreMessage(&second); err != nil {
+				t.Fatal(err)
+			}
+			store.Close()
+
+			store, err = db.NewFileStore(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+			checkMessages(t, store, "alice", []db.Message{message, second})
+		})
+	}
+}
+
+func TestCorruptLog(t *testing.T) {
+	dir := t.TempDir()
+	bad := `00000000 {"op":"store_user","user":{"username":"mallory"}}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "store.log"), []byte(bad+bad), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// a damaged record followed by others is not a torn write, the log is refused
+	if _, err := db.NewFileStore(dir); err == nil {
+		t.Fatal("a log damaged before its last record was opened")
+	}
+}
+
+// TestCompaction checks that rewriting the log keeps the messages of every author in their order,
+// with the updates and deletions applied, when the authors' messages are interleaved in the log
+func TestCompaction(t *testing.T) {
+	dir := t.TempDir()
+	store, err := db.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authors := []string{"alice", "bob", "carol"}
+	want := map[string][]db.Message{}
+	for i := 0; i < 30; i++ {
+		author := authors[i%len(authors)]
+		message := newMessage(author, i)
+		if err := store.StoreMessage(&message); err != nil {
+			t.Fatal(err)
+		}
+		want[author] = append(want[author], message)
+	}
+
+	// the first message of alice is updated and the second one of bob deleted
+	updated := want["alice"][0]
+	updated.EncryptedMessage = []byte("updated")
+	if err := store.UpdateMessage(&updated); err != nil {
+		t.Fatal(err)
+	}
+	want["alice"][0] = updated
+	if err := store.DeleteMessage(want["bob"][1].Id); err != nil {
+		t.Fatal(err)
+	}
+	want["bob"] = append(want["bob"][:1:1], want["bob"][2:]...)
+
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	for _, author := range authors {
+		checkMessages(t, store, author, want[author])
+	}
+	store.Close()
+
+	reopened, err := db.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	for _, author := range authors {
+		checkMessages(t, reopened, author, want[author])
+	}
+}
+
+// TestCompactionFailure blocks the temporary file of the compaction: the writes that trigger it must still succeed,
+// and the compaction must succeed later once it is unblocked
+func TestCompactionFailure(t *testing.T) {
+	dir := t.TempDir()
+	store, err := db.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := newUser("alice")
+	if err := store.StoreUser(&alice); err != nil {
+		t.Fatal(err)
+	}
+
+	blocker := filepath.Join(dir, "store.log.tmp")
+	if err := os.MkdirAll(filepath.Join(blocker, "keep"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Compact(); err == nil {
+		t.Fatal("Compact succeeded without its temporary file")
+	}
+
+	// every write beyond the threshold tries to compact the log, which fails
+	for i := 0; i < 1100; i++ {
+		alice.Password = fmt.Sprintf("hash %d", i)
+		if err := store.SetUser("alice", alice); err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+	checkUser(t, store, "alice", alice)
+
+	logPath := filepath.Join(dir, "store.log")
+	before, err := os.Stat(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(blocker); err != nil {
+		t.Fatal(err)
+	}
+	alice.Password = "final hash"
+	if err := store.SetUser("alice", alice); err != nil {
+		t.Fatal(err)
+	}
+	after, err := os.Stat(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Size() >= before.Size() {
+		t.Fatalf("the log was not compacted: %d bytes, %d before", after.Size(), before.Size())
+	}
+
+	// the writes after the compaction go to the new log
+	message := newMessage("alice", 0)
+	if err := store.StoreMessage(&message); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	reopened, err := db.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	checkUser(t, reopened, "alice", alice)
+	checkMessages(t, reopened, "alice", []db.Message{message})
+}
@@ -12,7 +12,7 @@ const (
 
 var CipherRoles = map[CipherChoice][]EncryptionAlg{
 	ClassicUser:    {Caesar, CaesarPerm, Playfair, Vigener},
-	AssymetricUser: {Rsa},
+	AssymetricUser: {Rsa, ElGamal},
 	SymmetricUser:  {Blowfish, OneTimePad},
 }
 
@@ -22,4 +22,6 @@ type User struct {
 	Password   string       `json:"password"`
 	Choice     CipherChoice `json:"choice"`
 	TOTPSecret string
+	// EncryptedKeys holds the keys of the user, sealed under the server master key
+	EncryptedKeys []byte `json:"-"`
 }
@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"github.com/EliriaT/CS-Labs/api/db"
 	"github.com/EliriaT/CS-Labs/api/service"
 	"github.com/EliriaT/CS-Labs/api/token"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"image/png"
 	"net/http"
+	"time"
 )
 
 type createUserRequest struct {
@@ -133,6 +135,23 @@ func (server *Server) twoFactorLoginUser(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, response)
 }
 
+// messageErrorStatus is the HTTP status of an error of the message service: a request that can never succeed is
+// a bad request, a message of another user is forbidden, a missing message or grant is not found, and the
+// errors of the keys or of the store are internal errors
+func messageErrorStatus(err error) int {
+	switch err {
+	case service.ErrInvalidAlg:
+		return http.StatusBadRequest
+	case service.ErrUnauthorized:
+		return http.StatusUnauthorized
+	case service.ErrUnauthorisedAlg, service.ErrNotAuthor, service.ErrNoAccess:
+		return http.StatusForbidden
+	case service.ErrMessageNotFound, service.ErrNoGrant:
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
 type createMessageRequest struct {
 	Message string      `json:"message" form:"message" binding:"required,min=4"`
 	Choice  json.Number `json:"choice" form:"choice" binding:"required"`
@@ -155,11 +174,7 @@ func (server *Server) createMessage(ctx *gin.Context) {
 
 	message, err := server.serv.StoreAndEncryptMessage(authPayload.Username, req.Message, int(choice))
 	if err != nil {
-		if err == service.ErrEncryption || err == service.ErrUUID {
-			ctx.JSON(http.StatusInternalServerError, ErrorResponse(err))
-			return
-		}
-		ctx.JSON(http.StatusUnauthorized, ErrorResponse(err))
+		ctx.JSON(messageErrorStatus(err), ErrorResponse(err))
 		return
 	}
 
@@ -167,28 +182,248 @@ func (server *Server) createMessage(ctx *gin.Context) {
 }
 
 func (server *Server) getUserMessageByID(ctx *gin.Context) {
-	messageID := ctx.Param("id")
+	messageID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
-	message, err := server.serv.GetMessageFromDB(authPayload.Username, uuid.MustParse(messageID))
+	message, err := server.serv.GetMessageFromDB(authPayload.Username, messageID)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, ErrorResponse(err))
+		ctx.JSON(messageErrorStatus(err), ErrorResponse(err))
 		return
 	}
 
 	ctx.JSON(http.StatusOK, message)
 }
 
+// listMessagesRequest holds the query parameters of a page of messages, the algorithm may be repeated
+type listMessagesRequest struct {
+	Algorithms []int     `form:"algorithm"`
+	From       time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To         time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Cursor     string    `form:"cursor"`
+	Limit      int       `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
 func (server *Server) getMessagesOfUser(ctx *gin.Context) {
+	var req listMessagesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+
+	filter := service.MessageFilter{
+		From:   req.From,
+		To:     req.To,
+		Cursor: req.Cursor,
+		Limit:  req.Limit,
+	}
+	for _, alg := range req.Algorithms {
+		filter.Algorithms = append(filter.Algorithms, db.EncryptionAlg(alg))
+	}
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
-	messages, err := server.serv.GetMessagesOfUser(authPayload.Username)
+	page, err := server.serv.ListMessages(authPayload.Username, filter)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, ErrorResponse(err))
+		if err == service.ErrInvalidCursor {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+			return
+		}
+		ctx.JSON(messageErrorStatus(err), ErrorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, page)
+}
+
+func (server *Server) updateMessage(ctx *gin.Context) {
+	messageID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+
+	var req createMessageRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+
+	choice, err := req.Choice.Int64()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	message, err := server.serv.UpdateMessage(authPayload.Username, messageID, req.Message, int(choice))
+	if err != nil {
+		ctx.JSON(messageErrorStatus(err), ErrorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, message)
+}
+
+func (server *Server) deleteMessage(ctx *gin.Context) {
+	messageID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	if err := server.serv.DeleteMessage(authPayload.Username, messageID); err != nil {
+		ctx.JSON(messageErrorStatus(err), ErrorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type shareMessageRequest struct {
+	Username string `json:"username" form:"username" binding:"required"`
+}
+
+func (server *Server) shareMessage(ctx *gin.Context) {
+	messageID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+
+	var req shareMessageRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	grant, err := server.serv.ShareMessage(authPayload.Username, messageID, req.Username)
+	if err != nil {
+		if err == service.ErrShareWithAuthor || err == service.ErrUnknownRecipient || err == service.ErrNotShareable {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+			return
+		}
+		ctx.JSON(messageErrorStatus(err), ErrorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, grant)
+}
+
+func (server *Server) getSharesOfMessage(ctx *gin.Context) {
+	messageID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	grants, err := server.serv.GetSharesOfMessage(authPayload.Username, messageID)
+	if err != nil {
+		ctx.JSON(messageErrorStatus(err), ErrorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, grants)
+}
+
+func (server *Server) revokeShare(ctx *gin.Context) {
+	messageID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	if err := server.serv.RevokeShare(authPayload.Username, messageID, ctx.Param("username")); err != nil {
+		ctx.JSON(messageErrorStatus(err), ErrorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (server *Server) getMessagesSharedWithUser(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	messages, err := server.serv.GetMessagesSharedWithUser(authPayload.Username)
+	if err != nil {
+		ctx.JSON(messageErrorStatus(err), ErrorResponse(err))
 		return
 	}
 
 	ctx.JSON(http.StatusOK, messages)
 }
+
+type breakCipherRequest struct {
+	Ciphertext string `json:"ciphertext" form:"ciphertext" binding:"required"`
+}
+
+func (server *Server) breakCaesar(ctx *gin.Context) {
+	var req breakCipherRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+
+	result, err := server.serv.BreakCaesar(req.Ciphertext)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+type tallyKeyResponse struct {
+	N []byte `json:"n"`
+}
+
+func (server *Server) getTallyKey(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, tallyKeyResponse{N: server.serv.TallyPublicKey()})
+}
+
+// addTallyRequest holds a value encrypted by the client with the tally key
+type addTallyRequest struct {
+	Ciphertext []byte `json:"ciphertext" binding:"required"`
+}
+
+type addTallyResponse struct {
+	Count int `json:"count"`
+}
+
+func (server *Server) addToTally(ctx *gin.Context) {
+	var req addTallyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+	count, err := server.serv.AddToTally(req.Ciphertext)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, addTallyResponse{Count: count})
+}
+
+func (server *Server) getTallyResult(ctx *gin.Context) {
+	result, err := server.serv.GetTallyResult()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
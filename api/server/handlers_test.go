@@ -0,0 +1,368 @@
+package server
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"encoding/json"
+	"github.com/EliriaT/CS-Labs/api/config"
+	"github.com/EliriaT/CS-Labs/api/db"
+	"github.com/EliriaT/CS-Labs/api/service"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/paillier"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	tallyKeyOnce sync.Once
+	tallyKey     *paillier.PrivateKey
+)
+
+// newTestServer returns a server on a memory store, with the classic users alice and bob registered
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	tallyKeyOnce.Do(func() {
+		var err error
+		if tallyKey, err = paillier.GenerateKey(crand.Reader, paillier.MinKeySize); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	masterKey := make([]byte, 32)
+	crand.Read(masterKey)
+	keyring, err := service.NewKeyring(masterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := db.NewStore()
+	serv := service.NewServerService(store, keyring, &tallyKey.PublicKey)
+	for _, username := range []string{"alice", "bob"} {
+		if _, _, err := serv.Register(username, "password", int(db.ClassicUser)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	configuration := config.Config{TokenSymmetricKey: "12345678901234567890123456789012", AccessTokenDuration: time.Minute}
+	server, err := NewServer(store, configuration, serv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+// tokenOf returns an access token of username that passed the second factor
+func tokenOf(t *testing.T, server *Server, username string) string {
+	t.Helper()
+	accessToken, err := server.tokenMaker.CreateToken(username, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := server.tokenMaker.VerifyToken(accessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accessToken, err = server.tokenMaker.AuthenticateToken(*payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return accessToken
+}
+
+func request(t *testing.T, server *Server, username, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reader).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(authorizationHeaderKey, "Bearer "+tokenOf(t, server, username))
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestMessageStatusCodes(t *testing.T) {
+	server := newTestServer(t)
+
+	created := request(t, server, "alice", http.MethodPost, "/message", gin.H{"message": "hello world", "choice": int(db.Caesar)})
+	if created.Code != http.StatusOK {
+		t.Fatalf("POST /message = %d %s", created.Code, created.Body)
+	}
+	var message db.Message
+	if err := json.Unmarshal(created.Body.Bytes(), &message); err != nil {
+		t.Fatal(err)
+	}
+	path := "/message/" + message.Id.String()
+	missing := "/message/" + uuid.NewString()
+
+	tests := []struct {
+		name     string
+		username string
+		method   string
+		path     string
+		body     any
+		want     int
+	}{
+		{"create with an unknown algorithm", "alice", http.MethodPost, "/message", gin.H{"message": "hello", "choice": 42}, http.StatusBadRequest},
+		{"create with an algorithm of another group", "alice", http.MethodPost, "/message", gin.H{"message": "hello", "choice": int(db.Blowfish)}, http.StatusForbidden},
+		{"read a bad id", "alice", http.MethodGet, "/message/not-an-id", nil, http.StatusBadRequest},
+		{"read a missing message", "alice", http.MethodGet, missing, nil, http.StatusNotFound},
+		{"read a message of another user", "bob", http.MethodGet, path, nil, http.StatusForbidden},
+		{"update with an unknown algorithm", "alice", http.MethodPut, path, gin.H{"message": "hello", "choice": 42}, http.StatusBadRequest},
+		{"update with an algorithm of another group", "alice", http.MethodPut, path, gin.H{"message": "hello", "choice": int(db.Blowfish)}, http.StatusForbidden},
+		{"update as another user", "bob", http.MethodPut, path, gin.H{"message": "hello", "choice": int(db.Caesar)}, http.StatusForbidden},
+		{"update a missing message", "alice", http.MethodPut, missing, gin.H{"message": "hello", "choice": int(db.Caesar)}, http.StatusNotFound},
+		{"delete as another user", "bob", http.MethodDelete, path, nil, http.StatusForbidden},
+		{"delete a missing message", "alice", http.MethodDelete, missing, nil, http.StatusNotFound},
+		{"share a missing message", "alice", http.MethodPost, missing + "/share", gin.H{"username": "bob"}, http.StatusNotFound},
+		{"share a message of another user", "bob", http.MethodPost, path + "/share", gin.H{"username": "bob"}, http.StatusForbidden},
+		{"revoke a missing grant", "alice", http.MethodDelete, path + "/share/bob", nil, http.StatusNotFound},
+		{"update", "alice", http.MethodPut, path, gin.H{"message": "hello again", "choice": int(db.Vigener)}, http.StatusOK},
+		{"read", "alice", http.MethodGet, path, nil, http.StatusOK},
+		{"delete", "alice", http.MethodDelete, path, nil, http.StatusNoContent},
+		{"read a deleted message", "alice", http.MethodGet, path, nil, http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		response := request(t, server, test.username, test.method, test.path, test.body)
+		if response.Code != test.want {
+			t.Errorf("%s: %s %s = %d %s, want %d", test.name, test.method, test.path, response.Code, response.Body, test.want)
+		}
+	}
+}
+
+func TestListMessages(t *testing.T) {
+	server := newTestServer(t)
+
+	var ids []uuid.UUID
+	for _, choice := range []db.EncryptionAlg{db.Caesar, db.Vigener, db.CaesarPerm} {
+		created := request(t, server, "alice", http.MethodPost, "/message", gin.H{"message": "hello world", "choice": int(choice)})
+		if created.Code != http.StatusOK {
+			t.Fatalf("POST /message = %d %s", created.Code, created.Body)
+		}
+		var message db.Message
+		if err := json.Unmarshal(created.Body.Bytes(), &message); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, message.Id)
+	}
+
+	for _, query := range []string{"algorithm=42", "algorithm=-1", "algorithm=caesar", "cursor=!", "limit=1000"} {
+		if response := request(t, server, "alice", http.MethodGet, "/message/all?"+query, nil); response.Code != http.StatusBadRequest {
+			t.Errorf("GET /message/all?%s = %d %s, want 400", query, response.Code, response.Body)
+		}
+	}
+
+	// the keys of the second message are damaged, it must be listed with an error rather than as an empty message
+	damaged, err := server.store.GetMessage(ids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	damaged.WrappedKey[len(damaged.WrappedKey)-1] ^= 1
+	if err := server.store.UpdateMessage(&damaged); err != nil {
+		t.Fatal(err)
+	}
+
+	response := request(t, server, "alice", http.MethodGet, "/message/all", nil)
+	if response.Code != http.StatusOK {
+		t.Fatalf("GET /message/all = %d %s", response.Code, response.Body)
+	}
+	var page service.MessagePage
+	if err := json.Unmarshal(response.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Messages) != len(ids) {
+		t.Fatalf("listed %d messages, want %d", len(page.Messages), len(ids))
+	}
+	for i, item := range page.Messages {
+		if item.Id != ids[i] {
+			t.Fatalf("message %d is %s, want %s", i, item.Id, ids[i])
+		}
+		if i == 1 {
+			if item.Error == "" || item.Plaintext != "" {
+				t.Errorf("the damaged message is listed as %+v", item)
+			}
+			continue
+		}
+		if item.Error != "" || item.Plaintext != "hello world" {
+			t.Errorf("message %d is listed as %+v", i, item)
+		}
+	}
+}
+
+// sharedPlaintexts returns the plaintexts of the messages shared with username, by message
+func sharedPlaintexts(t *testing.T, server *Server, username string) map[uuid.UUID]string {
+	t.Helper()
+	response := request(t, server, username, http.MethodGet, "/message/shared", nil)
+	if response.Code != http.StatusOK {
+		t.Fatalf("GET /message/shared as %s = %d %s", username, response.Code, response.Body)
+	}
+	var items []service.SharedMessageItem
+	if err := json.Unmarshal(response.Body.Bytes(), &items); err != nil {
+		t.Fatal(err)
+	}
+	plaintexts := map[uuid.UUID]string{}
+	for _, item := range items {
+		plaintexts[item.Id] = item.Plaintext
+	}
+	return plaintexts
+}
+
+func TestListSharedMessages(t *testing.T) {
+	server := newTestServer(t)
+
+	var ids []uuid.UUID
+	for i := 0; i < 2; i++ {
+		created := request(t, server, "alice", http.MethodPost, "/message", gin.H{"message": "hello world", "choice": int(db.Caesar)})
+		if created.Code != http.StatusOK {
+			t.Fatalf("POST /message = %d %s", created.Code, created.Body)
+		}
+		var message db.Message
+		if err := json.Unmarshal(created.Body.Bytes(), &message); err != nil {
+			t.Fatal(err)
+		}
+		path := "/message/" + message.Id.String() + "/share"
+		if response := request(t, server, "alice", http.MethodPost, path, gin.H{"username": "bob"}); response.Code != http.StatusOK {
+			t.Fatalf("POST %s = %d %s", path, response.Code, response.Body)
+		}
+		ids = append(ids, message.Id)
+	}
+
+	// the grant of the second message is damaged, it must be listed with an error rather than as an empty message
+	damaged, err := server.store.GetGrant(ids[1], "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	damaged.WrappedKey[len(damaged.WrappedKey)-1] ^= 1
+	if err := server.store.StoreGrant(&damaged); err != nil {
+		t.Fatal(err)
+	}
+
+	response := request(t, server, "bob", http.MethodGet, "/message/shared", nil)
+	if response.Code != http.StatusOK {
+		t.Fatalf("GET /message/shared = %d %s", response.Code, response.Body)
+	}
+	var items []service.SharedMessageItem
+	if err := json.Unmarshal(response.Body.Bytes(), &items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != len(ids) {
+		t.Fatalf("listed %d messages, want %d", len(items), len(ids))
+	}
+	for _, item := range items {
+		switch {
+		case item.Id == ids[1] && (item.Error == "" || item.Plaintext != ""):
+			t.Errorf("the damaged message is listed as %+v", item)
+		case item.Id == ids[0] && (item.Error != "" || item.Plaintext != "hello world"):
+			t.Errorf("the message is listed as %+v", item)
+		}
+	}
+}
+
+// TestUpdateWhileSharing updates a message while it is shared and revoked, a grant left afterwards must open
+// the last content
+func TestUpdateWhileSharing(t *testing.T) {
+	server := newTestServer(t)
+
+	created := request(t, server, "alice", http.MethodPost, "/message", gin.H{"message": "version", "choice": int(db.Caesar)})
+	if created.Code != http.StatusOK {
+		t.Fatalf("POST /message = %d %s", created.Code, created.Body)
+	}
+	var message db.Message
+	if err := json.Unmarshal(created.Body.Bytes(), &message); err != nil {
+		t.Fatal(err)
+	}
+	path := "/message/" + message.Id.String()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= 20; i++ {
+			request(t, server, "alice", http.MethodPut, path, gin.H{"message": "version " + strings.Repeat("i", i), "choice": int(db.Caesar)})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			request(t, server, "alice", http.MethodPost, path+"/share", gin.H{"username": "bob"})
+			if i%3 == 2 {
+				request(t, server, "alice", http.MethodDelete, path+"/share/bob", nil)
+			}
+		}
+		request(t, server, "alice", http.MethodPost, path+"/share", gin.H{"username": "bob"})
+	}()
+	wg.Wait()
+
+	if got := sharedPlaintexts(t, server, "bob")[message.Id]; got != "version "+strings.Repeat("i", 20) {
+		t.Fatalf("bob reads %q, want the last version", got)
+	}
+}
+
+// TestShareAsymmetricMessages shares an RSA message with an asymmetric user, whose grant is an envelope,
+// and a classic one, whose grant is sealed under their key encryption key
+func TestShareAsymmetricMessages(t *testing.T) {
+	server := newTestServer(t)
+	for _, username := range []string{"carol", "dave"} {
+		if _, _, err := server.serv.Register(username, "password", int(db.AssymetricUser)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	created := request(t, server, "carol", http.MethodPost, "/message", gin.H{"message": "hello world", "choice": int(db.Rsa)})
+	if created.Code != http.StatusOK {
+		t.Fatalf("POST /message = %d %s", created.Code, created.Body)
+	}
+	var message db.Message
+	if err := json.Unmarshal(created.Body.Bytes(), &message); err != nil {
+		t.Fatal(err)
+	}
+	path := "/message/" + message.Id.String()
+
+	recipients := []string{"dave", "bob"}
+	for _, recipient := range recipients {
+		if response := request(t, server, "carol", http.MethodPost, path+"/share", gin.H{"username": recipient}); response.Code != http.StatusOK {
+			t.Fatalf("sharing with %s = %d %s", recipient, response.Code, response.Body)
+		}
+	}
+	check := func(want string) {
+		t.Helper()
+		for _, recipient := range recipients {
+			if got := sharedPlaintexts(t, server, recipient)[message.Id]; got != want {
+				t.Errorf("%s reads %q, want %q", recipient, got, want)
+			}
+		}
+	}
+	check("hello world")
+
+	// the grants are sealed again for the new envelope
+	if response := request(t, server, "carol", http.MethodPut, path, gin.H{"message": "hello again", "choice": int(db.Rsa)}); response.Code != http.StatusOK {
+		t.Fatalf("PUT %s = %d %s", path, response.Code, response.Body)
+	}
+	check("hello again")
+
+	// an ElGamal message can not be shared, so updating to ElGamal drops the grants
+	if response := request(t, server, "carol", http.MethodPut, path, gin.H{"message": "hello elgamal", "choice": int(db.ElGamal)}); response.Code != http.StatusOK {
+		t.Fatalf("PUT %s = %d %s", path, response.Code, response.Body)
+	}
+	for _, recipient := range recipients {
+		if _, ok := sharedPlaintexts(t, server, recipient)[message.Id]; ok {
+			t.Errorf("the message is still shared with %s", recipient)
+		}
+	}
+	if response := request(t, server, "carol", http.MethodPost, path+"/share", gin.H{"username": "dave"}); response.Code != http.StatusBadRequest {
+		t.Errorf("sharing an ElGamal message = %d %s, want 400", response.Code, response.Body)
+	}
+}
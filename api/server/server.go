@@ -46,6 +46,22 @@ func (server *Server) setupRouter() {
 	authRoutes.POST("", server.createMessage)
 	authRoutes.GET("/:id", server.getUserMessageByID)
 	authRoutes.GET("/all", server.getMessagesOfUser)
+	authRoutes.PUT("/:id", server.updateMessage)
+	authRoutes.DELETE("/:id", server.deleteMessage)
+	authRoutes.GET("/shared", server.getMessagesSharedWithUser)
+	authRoutes.POST("/:id/share", server.shareMessage)
+	authRoutes.GET("/:id/share", server.getSharesOfMessage)
+	authRoutes.DELETE("/:id/share/:username", server.revokeShare)
+
+	cryptanalysisRoutes := router.Group("/cryptanalysis").Use(AuthMiddleware(server.tokenMaker))
+
+	cryptanalysisRoutes.POST("/caesar", server.breakCaesar)
+
+	tallyRoutes := router.Group("/tally").Use(AuthMiddleware(server.tokenMaker))
+
+	tallyRoutes.GET("/key", server.getTallyKey)
+	tallyRoutes.POST("", server.addToTally)
+	tallyRoutes.GET("", server.getTallyResult)
 
 	server.router = router
 }
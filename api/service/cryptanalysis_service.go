@@ -0,0 +1,20 @@
+package service
+
+import (
+	"github.com/EliriaT/CS-Labs/classicCipher/cryptanalysis"
+)
+
+type CryptanalysisService interface {
+	BreakCaesar(ciphertext string) (cryptanalysis.CaesarResult, error)
+}
+
+type cryptanalysisService struct {
+}
+
+func (c *cryptanalysisService) BreakCaesar(ciphertext string) (cryptanalysis.CaesarResult, error) {
+	return cryptanalysis.BreakCaesar(ciphertext)
+}
+
+func NewCryptanalysisService() CryptanalysisService {
+	return &cryptanalysisService{}
+}
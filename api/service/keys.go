@@ -0,0 +1,312 @@
+package service
+
+import (
+	"crypto/cipher"
+	crand "crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/EliriaT/CS-Labs/api/db"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/elgamal"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/envelope"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/rsa"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/chacha20poly1305"
+	"math/big"
+	"strings"
+)
+
+var (
+	ErrNoMasterKey = errors.New("MASTER_KEY is not set")
+	ErrMasterKey   = errors.New("master key must be 32 bytes long, hex or base64 encoded")
+	ErrKeys        = errors.New("Can not open the stored keys")
+)
+
+// kekSize is the size of the key encryption key of every user, which seals the keys of their messages
+const kekSize = chacha20poly1305.KeySize
+
+// Keyring seals the key material of the users under the server master key.
+// Every user has a key encryption key and, for the asymmetric users, their own RSA and ElGamal keys;
+// every message has its own keys, sealed under the key encryption key of its author, and sealed again
+// for every user the message is shared with.
+type Keyring struct {
+	master cipher.AEAD
+}
+
+// userKeys is the key material of a user, stored sealed in db.User.EncryptedKeys
+type userKeys struct {
+	KEK        []byte              `json:"kek"`
+	RSAKey     []byte              `json:"rsa_key,omitempty"` // PKCS #8
+	ElGamalKey *elgamal.PrivateKey `json:"elgamal_key,omitempty"`
+}
+
+// messageKey is the key material of a message, stored sealed in db.Message.WrappedKey
+type messageKey struct {
+	Shift    int    `json:"shift,omitempty"`    // Caesar and Caesar with permutation
+	Alphabet string `json:"alphabet,omitempty"` // Caesar with permutation
+	Keyword  string `json:"keyword,omitempty"`  // Vigenere and Playfair
+	Key      []byte `json:"key,omitempty"`      // Blowfish key or one-time pad
+}
+
+// sharedKey is what the recipient of a grant needs to read a message, stored sealed in db.Grant.WrappedKey
+type sharedKey struct {
+	MessageId uuid.UUID   `json:"message_id"`
+	Key       *messageKey `json:"key,omitempty"`
+	// DataKey is set for the RSA messages: the data key of their envelope, which opens that envelope only
+	DataKey []byte `json:"data_key,omitempty"`
+}
+
+// DecodeMasterKey decodes the master key from its hex or base64 form, which must give 32 bytes
+func DecodeMasterKey(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+	if encoded == "" {
+		return nil, ErrNoMasterKey
+	}
+
+	if key, err := hex.DecodeString(encoded); err == nil && len(key) == chacha20poly1305.KeySize {
+		return key, nil
+	}
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if key, err := encoding.DecodeString(encoded); err == nil && len(key) == chacha20poly1305.KeySize {
+			return key, nil
+		}
+	}
+	return nil, ErrMasterKey
+}
+
+// NewKeyring returns a keyring using the 32-byte masterKey
+func NewKeyring(masterKey []byte) (*Keyring, error) {
+	if len(masterKey) != chacha20poly1305.KeySize {
+		return nil, ErrMasterKey
+	}
+	master, err := chacha20poly1305.NewX(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Keyring{master: master}, nil
+}
+
+// NewUserKeys generates the keys of a new user and returns them sealed
+func (k *Keyring) NewUserKeys(userId uuid.UUID, choice db.CipherChoice) ([]byte, error) {
+	keys := userKeys{KEK: make([]byte, kekSize)}
+	if _, err := crand.Read(keys.KEK); err != nil {
+		return nil, err
+	}
+
+	if choice == db.AssymetricUser {
+		rsaKey, err := rsa.GenerateKey(crand.Reader, rsa.DefaultKeySize)
+		if err != nil {
+			return nil, err
+		}
+		keys.RSAKey, err = rsa.MarshalPKCS8PrivateKey(rsaKey)
+		if err != nil {
+			return nil, err
+		}
+		keys.ElGamalKey, err = elgamal.GenerateKey(crand.Reader, elgamal.Group14())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	plaintext, err := json.Marshal(keys)
+	if err != nil {
+		return nil, err
+	}
+	return seal(k.master, plaintext, userId[:])
+}
+
+// userKeys opens the keys of user
+func (k *Keyring) userKeys(user db.User) (*userKeys, error) {
+	plaintext, err := open(k.master, user.EncryptedKeys, user.Id[:])
+	if err != nil {
+		return nil, ErrKeys
+	}
+	var keys userKeys
+	if err := json.Unmarshal(plaintext, &keys); err != nil {
+		return nil, ErrKeys
+	}
+	return &keys, nil
+}
+
+// sealMessageKey seals the key of the message messageId under the key encryption key of its author
+func (u *userKeys) sealMessageKey(messageId uuid.UUID, key *messageKey) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(u.KEK)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+	return seal(aead, plaintext, messageId[:])
+}
+
+// openMessageKey opens the key of message
+func (u *userKeys) openMessageKey(message db.Message) (*messageKey, error) {
+	aead, err := chacha20poly1305.NewX(u.KEK)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(aead, message.WrappedKey, message.Id[:])
+	if err != nil {
+		return nil, ErrKeys
+	}
+	var key messageKey
+	if err := json.Unmarshal(plaintext, &key); err != nil {
+		return nil, ErrKeys
+	}
+	return &key, nil
+}
+
+// sealSharedKey seals key for recipient: to their RSA public key when they have one, under their key encryption key otherwise
+func (k *Keyring) sealSharedKey(recipient db.User, key *sharedKey) ([]byte, error) {
+	keys, err := k.userKeys(recipient)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if keys.RSAKey != nil {
+		rsaKey, err := rsa.ParsePKCS8PrivateKey(keys.RSAKey)
+		if err != nil {
+			return nil, ErrKeys
+		}
+		return envelope.Seal(envelope.RSARecipient(&rsaKey.PublicKey), plaintext)
+	}
+	aead, err := chacha20poly1305.NewX(keys.KEK)
+	if err != nil {
+		return nil, err
+	}
+	return seal(aead, plaintext, grantAdditionalData(key.MessageId, recipient.Username))
+}
+
+// openSharedKey opens the key of grant with the keys of its recipient
+func (k *Keyring) openSharedKey(recipient db.User, grant db.Grant) (*sharedKey, error) {
+	keys, err := k.userKeys(recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	var plaintext []byte
+	if keys.RSAKey != nil {
+		rsaKey, err := rsa.ParsePKCS8PrivateKey(keys.RSAKey)
+		if err != nil {
+			return nil, ErrKeys
+		}
+		plaintext, err = envelope.Open(envelope.RSAIdentity(rsaKey), grant.WrappedKey)
+		if err != nil {
+			return nil, ErrKeys
+		}
+	} else {
+		aead, err := chacha20poly1305.NewX(keys.KEK)
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err = open(aead, grant.WrappedKey, grantAdditionalData(grant.MessageId, recipient.Username))
+		if err != nil {
+			return nil, ErrKeys
+		}
+	}
+
+	var key sharedKey
+	if err := json.Unmarshal(plaintext, &key); err != nil {
+		return nil, ErrKeys
+	}
+	// the envelope does not bind the key to its message, the id inside it does
+	if key.MessageId != grant.MessageId {
+		return nil, ErrKeys
+	}
+	return &key, nil
+}
+
+// grantAdditionalData binds a sealed shared key to its message and its recipient
+func grantAdditionalData(messageId uuid.UUID, recipient string) []byte {
+	return append(messageId[:], recipient...)
+}
+
+// newMessageKey generates fresh keys for a message of size bytes encrypted with alg.
+// The asymmetric algorithms use the keys of the user and need none.
+func newMessageKey(alg db.EncryptionAlg, size int) (*messageKey, error) {
+	var key messageKey
+	var err error
+	switch alg {
+	case db.Caesar:
+		key.Shift, err = randomInt(25)
+		key.Shift++
+	case db.CaesarPerm:
+		if key.Shift, err = randomInt(26); err == nil {
+			key.Alphabet, err = randomPermutation("ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+		}
+	case db.Vigener, db.Playfair:
+		key.Keyword, err = randomKeyword(16)
+	case db.Blowfish:
+		key.Key = make([]byte, 16)
+		_, err = crand.Read(key.Key)
+	case db.OneTimePad:
+		// the pad is as long as the message, and used for it only
+		if size == 0 {
+			size = 1
+		}
+		key.Key = make([]byte, size)
+		_, err = crand.Read(key.Key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// seal encrypts plaintext with a random nonce, which is prepended to the result
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := crand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open reverses seal
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrKeys
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additionalData)
+}
+
+// randomInt returns a uniform random number in [0, n)
+func randomInt(n int) (int, error) {
+	r, err := crand.Int(crand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(r.Int64()), nil
+}
+
+func randomKeyword(length int) (string, error) {
+	keyword := make([]byte, length)
+	for i := range keyword {
+		r, err := randomInt(26)
+		if err != nil {
+			return "", err
+		}
+		keyword[i] = byte('A' + r)
+	}
+	return string(keyword), nil
+}
+
+// randomPermutation shuffles letters with the Fisher-Yates algorithm
+func randomPermutation(letters string) (string, error) {
+	permutation := []byte(letters)
+	for i := len(permutation) - 1; i > 0; i-- {
+		j, err := randomInt(i + 1)
+		if err != nil {
+			return "", err
+		}
+		permutation[i], permutation[j] = permutation[j], permutation[i]
+	}
+	return string(permutation), nil
+}
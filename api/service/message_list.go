@@ -0,0 +1,128 @@
+package service
+
+import (
+	"encoding/base64"
+	"github.com/EliriaT/CS-Labs/api/db"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"time"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+var ErrInvalidCursor = errors.New("Invalid cursor")
+
+// MessageFilter selects the messages returned by ListMessages
+type MessageFilter struct {
+	// Algorithms keeps only the messages encrypted with one of them, all are kept when it is empty
+	Algorithms []db.EncryptionAlg
+	// From and To bound the creation time, From included and To excluded. A zero time is no bound.
+	From time.Time
+	To   time.Time
+	// Cursor is the NextCursor of the previous page, empty for the first page
+	Cursor string
+	// Limit is the size of the page, DefaultPageSize when 0 and at most MaxPageSize
+	Limit int
+}
+
+// MessageItem is a decrypted message of a page. A message whose keys can not be opened or which does not decrypt
+// is still listed, with an empty Plaintext and the reason in Error, so that it is not mistaken for an empty message.
+type MessageItem struct {
+	Id        uuid.UUID `json:"id"`
+	Algorithm string    `json:"algorithm"`
+	CreatedAt time.Time `json:"created_at"`
+	Plaintext string    `json:"plaintext"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// MessagePage is a page of decrypted messages, NextCursor is empty on the last page
+type MessagePage struct {
+	Messages   []MessageItem `json:"messages"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// ListMessages returns a page of the decrypted messages of username, oldest first
+func (m *messageService) ListMessages(username string, filter MessageFilter) (MessagePage, error) {
+	user, err := m.db.GetUser(username)
+	if err != nil {
+		return MessagePage{}, ErrUnauthorized
+	}
+
+	for _, alg := range filter.Algorithms {
+		if alg < db.Rsa || alg > db.ElGamal {
+			return MessagePage{}, ErrInvalidAlg
+		}
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	query := db.MessageQuery{
+		Author:     username,
+		Algorithms: filter.Algorithms,
+		From:       filter.From,
+		To:         filter.To,
+		// one more message tells whether there is a next page
+		Limit: limit + 1,
+	}
+	if filter.Cursor != "" {
+		cursor, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return MessagePage{}, err
+		}
+		query.After = &cursor
+	}
+
+	messages, err := m.db.ListMessages(query)
+	if err != nil {
+		return MessagePage{}, err
+	}
+
+	page := MessagePage{Messages: []MessageItem{}}
+	if len(messages) > limit {
+		messages = messages[:limit]
+		page.NextCursor = encodeCursor(db.CursorOf(messages[limit-1]))
+	}
+	for _, message := range messages {
+		item := MessageItem{
+			Id:        message.Id,
+			Algorithm: message.EncryptionAlg.String(),
+			CreatedAt: message.CreatedAt,
+		}
+		if decrypted := m.decryptMessage(user, message); decrypted != nil {
+			item.Plaintext = string(decrypted)
+		} else {
+			item.Error = ErrEncryption.Error()
+		}
+		page.Messages = append(page.Messages, item)
+	}
+	return page, nil
+}
+
+// encodeCursor encodes cursor as the id of the message followed by its creation time
+func encodeCursor(cursor db.MessageCursor) string {
+	createdAt, _ := cursor.CreatedAt.MarshalBinary()
+	return base64.RawURLEncoding.EncodeToString(append(cursor.Id[:], createdAt...))
+}
+
+func decodeCursor(encoded string) (db.MessageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(data) < len(uuid.UUID{}) {
+		return db.MessageCursor{}, ErrInvalidCursor
+	}
+
+	var cursor db.MessageCursor
+	copy(cursor.Id[:], data)
+	if err := cursor.CreatedAt.UnmarshalBinary(data[len(cursor.Id):]); err != nil {
+		return db.MessageCursor{}, ErrInvalidCursor
+	}
+	return cursor, nil
+}
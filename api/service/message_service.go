@@ -1,21 +1,23 @@
 package service
 
 import (
-	"fmt"
+	crand "crypto/rand"
 	"github.com/EliriaT/CS-Labs/api/db"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/elgamal"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/envelope"
 	"github.com/EliriaT/CS-Labs/asymetricCipher/rsa"
 	"github.com/EliriaT/CS-Labs/classicCipher/Caesar"
 	"github.com/EliriaT/CS-Labs/classicCipher/CaesarPermutation"
 	"github.com/EliriaT/CS-Labs/classicCipher/Playfair"
 	"github.com/EliriaT/CS-Labs/classicCipher/Vigener"
 	"github.com/EliriaT/CS-Labs/streamBlockCipher/blowfish"
+	"github.com/EliriaT/CS-Labs/streamBlockCipher/modes"
 	"github.com/EliriaT/CS-Labs/streamBlockCipher/oneTimePad"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
-	"log"
-	"math/rand"
-	"strconv"
-	"strings"
+	"math/big"
+	"sync"
+	"time"
 )
 
 // Different types of error returned by the VerifyToken function
@@ -25,40 +27,137 @@ var (
 	ErrEncryption      = errors.New("Unknown encryption or decryption error")
 	ErrUUID            = errors.New("UUID error")
 	ErrUnauthorized    = errors.New("User is not authorized ")
-)
-
-var (
-	caesarCipher            Caesar.CaesarCipher
-	caesarPermutationCipher CaesarPermutation.CaesarPermutationCipher
-	vigenereCipher          Vigener.VigenereCipher
-	playfairCipher          Playfair.PlayfairCipher
-	blowfishCipher          *blowfish.Blowfish
-	otpCipher               *oneTimePad.Pad
-	rsaCipher               rsa.RSA
+	ErrMessageNotFound = errors.New("Message not found")
+	ErrNotAuthor       = errors.New("Only the author can change this message")
+	ErrNoAccess        = errors.New("The message is neither written by nor shared with this user")
 )
 
 type MessageService interface {
 	StoreAndEncryptMessage(username string, message string, encryptAlgorithm int) (db.Message, error)
 	GetMessageFromDB(username string, messageID uuid.UUID) (string, error)
-	GetMessagesOfUser(username string) ([]string, error)
+	ListMessages(username string, filter MessageFilter) (MessagePage, error)
+	UpdateMessage(username string, messageID uuid.UUID, message string, encryptAlgorithm int) (db.Message, error)
+	DeleteMessage(username string, messageID uuid.UUID) error
+	ShareMessage(username string, messageID uuid.UUID, recipient string) (db.Grant, error)
+	RevokeShare(username string, messageID uuid.UUID, recipient string) error
+	GetSharesOfMessage(username string, messageID uuid.UUID) ([]db.Grant, error)
+	GetMessagesSharedWithUser(username string) ([]SharedMessageItem, error)
 }
 
 type messageService struct {
-	db db.Store
+	db      db.Store
+	keyring *Keyring
+	// sharesMu serializes the updates and deletions of messages with the changes of their grants, so that no grant
+	// is stored or revoked between reading the grants of a message and replacing its content
+	sharesMu sync.Mutex
 }
 
-func NewMessageService(database db.Store) MessageService {
-	return &messageService{db: database}
+func NewMessageService(database db.Store, keyring *Keyring) MessageService {
+	return &messageService{db: database, keyring: keyring}
 }
 
 func (m *messageService) StoreAndEncryptMessage(username string, message string, encryptAlgorithm int) (db.Message, error) {
 	user, err := m.db.GetUser(username)
 	if err != nil {
+		return db.Message{}, ErrUnauthorized
+	}
+
+	if err := checkAlgorithm(user, encryptAlgorithm); err != nil {
 		return db.Message{}, err
 	}
 
-	if encryptAlgorithm < int(db.Rsa) || encryptAlgorithm > int(db.OneTimePad) {
-		return db.Message{}, ErrInvalidAlg
+	messageId, err := uuid.NewRandom()
+	if err != nil {
+		return db.Message{}, ErrUUID
+	}
+
+	dbMessage, err := m.encryptForUser(user, messageId, message, db.EncryptionAlg(encryptAlgorithm))
+	if err != nil {
+		return db.Message{}, err
+	}
+	dbMessage.CreatedAt = time.Now().UTC()
+	if err := m.db.StoreMessage(&dbMessage); err != nil {
+		return db.Message{}, err
+	}
+	return dbMessage, nil
+}
+
+// UpdateMessage replaces the content of a message of username, encrypting it again with new keys,
+// possibly under another algorithm the user may use
+func (m *messageService) UpdateMessage(username string, messageID uuid.UUID, message string, encryptAlgorithm int) (db.Message, error) {
+	m.sharesMu.Lock()
+	defer m.sharesMu.Unlock()
+	user, old, err := m.getOwnMessage(username, messageID)
+	if err != nil {
+		return db.Message{}, err
+	}
+
+	if err := checkAlgorithm(user, encryptAlgorithm); err != nil {
+		return db.Message{}, err
+	}
+
+	dbMessage, err := m.encryptForUser(user, messageID, message, db.EncryptionAlg(encryptAlgorithm))
+	if err != nil {
+		return db.Message{}, err
+	}
+	dbMessage.CreatedAt = old.CreatedAt
+
+	// the grants hold the keys of the old content: they are sealed again for the new one before it is stored,
+	// and stored or dropped only once it is
+	resealed, dropped, err := m.resealGrants(user, dbMessage)
+	if err != nil {
+		return db.Message{}, err
+	}
+	if err := m.db.UpdateMessage(&dbMessage); err != nil {
+		return db.Message{}, err
+	}
+	if err := m.reshare(resealed, dropped); err != nil {
+		return db.Message{}, err
+	}
+	return dbMessage, nil
+}
+
+// DeleteMessage deletes a message of username, and its grants
+func (m *messageService) DeleteMessage(username string, messageID uuid.UUID) error {
+	m.sharesMu.Lock()
+	defer m.sharesMu.Unlock()
+	if _, _, err := m.getOwnMessage(username, messageID); err != nil {
+		return err
+	}
+	return m.db.DeleteMessage(messageID)
+}
+
+// getOwnMessage returns the user and the message, if the user is its author
+func (m *messageService) getOwnMessage(username string, messageID uuid.UUID) (db.User, db.Message, error) {
+	user, err := m.db.GetUser(username)
+	if err != nil {
+		return db.User{}, db.Message{}, ErrUnauthorized
+	}
+
+	message, err := m.getMessage(messageID)
+	if err != nil {
+		return db.User{}, db.Message{}, err
+	}
+
+	if message.Author != username {
+		return db.User{}, db.Message{}, ErrNotAuthor
+	}
+	return user, message, nil
+}
+
+// getMessage returns the stored message, ErrMessageNotFound telling a missing message apart from a failing store
+func (m *messageService) getMessage(messageID uuid.UUID) (db.Message, error) {
+	message, err := m.db.GetMessage(messageID)
+	if errors.Is(err, db.ErrNotFound) {
+		return db.Message{}, ErrMessageNotFound
+	}
+	return message, err
+}
+
+// checkAlgorithm checks that encryptAlgorithm exists and that the cipher group of user allows it
+func checkAlgorithm(user db.User, encryptAlgorithm int) error {
+	if encryptAlgorithm < int(db.Rsa) || encryptAlgorithm > int(db.ElGamal) {
+		return ErrInvalidAlg
 	}
 
 	chiperGroup := user.Choice
@@ -69,167 +168,247 @@ func (m *messageService) StoreAndEncryptMessage(username string, message string,
 		}
 	}
 	if isPresent == false {
-		return db.Message{}, ErrUnauthorisedAlg
+		return ErrUnauthorisedAlg
 	}
+	return nil
+}
 
-	encryptedMessage := encryptMessage(db.EncryptionAlg(encryptAlgorithm), message)
-	if encryptedMessage == nil {
-		return db.Message{}, ErrEncryption
+// encryptForUser encrypts message under new keys, which are sealed for its author user
+func (m *messageService) encryptForUser(user db.User, messageId uuid.UUID, message string, alg db.EncryptionAlg) (db.Message, error) {
+	keys, err := m.keyring.userKeys(user)
+	if err != nil {
+		return db.Message{}, err
 	}
-
-	messageId, err := uuid.NewRandom()
+	key, err := newMessageKey(alg, len(message))
 	if err != nil {
-		return db.Message{}, ErrUUID
+		return db.Message{}, err
+	}
+	wrappedKey, err := keys.sealMessageKey(messageId, key)
+	if err != nil {
+		return db.Message{}, err
 	}
 
-	dbMessage := db.Message{
+	encryptedMessage := encryptMessage(alg, message, keys, key)
+	if encryptedMessage == nil {
+		return db.Message{}, ErrEncryption
+	}
+
+	return db.Message{
 		Id:               messageId,
 		EncryptedMessage: encryptedMessage,
-		EncryptionAlg:    db.EncryptionAlg(encryptAlgorithm),
-		Author:           username,
-	}
-	m.db.StoreMessage(&dbMessage)
-	return dbMessage, nil
+		EncryptionAlg:    alg,
+		Author:           user.Username,
+		WrappedKey:       wrappedKey,
+	}, nil
 }
 
+// GetMessageFromDB returns a decrypted message of username, or a message shared with username
 func (m *messageService) GetMessageFromDB(username string, messageID uuid.UUID) (string, error) {
-	_, err := m.db.GetUser(username)
+	user, err := m.db.GetUser(username)
 	if err != nil {
 		return "", ErrUnauthorized
 	}
-
-	message, err := m.db.GetMessage(messageID)
+	message, err := m.getMessage(messageID)
 	if err != nil {
-		return "", ErrUnauthorized
+		return "", err
 	}
 
-	if message.Author != username {
-		return "", ErrUnauthorized
+	var decrypted []byte
+	if message.Author == username {
+		decrypted = m.decryptMessage(user, message)
+	} else {
+		grant, err := m.db.GetGrant(messageID, username)
+		if errors.Is(err, db.ErrNotFound) {
+			return "", ErrNoAccess
+		}
+		if err != nil {
+			return "", err
+		}
+		decrypted = m.decryptSharedMessage(user, grant, message)
 	}
-
-	decrypted := decryptMessage(message.EncryptionAlg, message)
 	if decrypted == nil {
 		return "", ErrEncryption
 	}
 	return string(decrypted), nil
-
 }
 
-func (m *messageService) GetMessagesOfUser(username string) ([]string, error) {
-	_, err := m.db.GetUser(username)
+// decryptMessage opens the keys of message with the keys of its author and decrypts it
+func (m *messageService) decryptMessage(author db.User, message db.Message) []byte {
+	keys, err := m.keyring.userKeys(author)
 	if err != nil {
-		return nil, ErrUnauthorized
+		return nil
 	}
-
-	userMessages, err := m.db.GetMessagesOfUser(username)
+	key, err := keys.openMessageKey(message)
 	if err != nil {
-		return nil, ErrUnauthorized
+		return nil
 	}
-
-	var messages []string
-
-	for _, message := range userMessages {
-		bytes := decryptMessage(message.EncryptionAlg, message)
-		messages = append(messages, string(bytes))
-	}
-	return messages, nil
+	return decryptWithKeys(message, keys, key)
 }
 
-func decryptMessage(alg db.EncryptionAlg, message db.Message) []byte {
-
-	switch alg {
+// decryptWithKeys decrypts message with its keys, or the keys of its author for the asymmetric algorithms
+func decryptWithKeys(message db.Message, keys *userKeys, key *messageKey) []byte {
+	switch message.EncryptionAlg {
 	case db.Rsa:
-		encNumsStr := strings.Split(strings.Trim(string(message.EncryptedMessage), "[]"), " ")
-		encNums := make([]int64, 0)
-		for _, num := range encNumsStr {
-			numInt, _ := strconv.Atoi(num)
-			encNums = append(encNums, int64(numInt))
+		rsaKey, err := rsa.ParsePKCS8PrivateKey(keys.RSAKey)
+		if err != nil {
+			return nil
 		}
-		decryptedMessage, _ := rsaCipher.Decrypt(encNums)
+		decryptedMessage, _ := envelope.Open(envelope.RSAIdentity(rsaKey), message.EncryptedMessage)
 		return decryptedMessage
 	case db.Caesar:
+		caesarCipher := Caesar.MakeCaesarCipher()
+		caesarCipher.SetKey(key.Shift)
 		decryptedMessage := caesarCipher.Decrypt(string(message.EncryptedMessage))
 		return []byte(decryptedMessage)
 	case db.CaesarPerm:
+		caesarPermutationCipher := makeCaesarPermutationCipher(key)
 		decryptedMessage := caesarPermutationCipher.Decrypt(string(message.EncryptedMessage))
 		return []byte(decryptedMessage)
 	case db.Playfair:
+		playfairCipher := Playfair.MakePlayfairCipher(key.Keyword)
 		decryptedMessage := playfairCipher.Decrypt(string(message.EncryptedMessage))
 		return []byte(decryptedMessage)
 	case db.Vigener:
+		vigenereCipher := Vigener.MakeVigenereCipher(key.Keyword)
 		decryptedMessage := vigenereCipher.Decrypt(string(message.EncryptedMessage))
 		return []byte(decryptedMessage)
 	case db.Blowfish:
-		decryptedMessage, _ := blowfishCipher.Decrypt(message.EncryptedMessage)
+		decryptedMessage, _ := decryptBlowfish(key.Key, message.EncryptedMessage)
 		return decryptedMessage
 	case db.OneTimePad:
+		otpCipher, err := oneTimePad.NewPad(key.Key, len(key.Key), 1)
+		if err != nil {
+			return nil
+		}
 		decryptedMessage, _ := otpCipher.Decrypt(message.EncryptedMessage)
 		return decryptedMessage
+	case db.ElGamal:
+		if keys.ElGamalKey == nil {
+			return nil
+		}
+		decryptedMessage, _ := decryptElGamal(elgamal.ElGamal{PrivateKey: *keys.ElGamalKey}, message.EncryptedMessage)
+		return decryptedMessage
 
 	}
 	return nil
 }
 
-func encryptMessage(alg db.EncryptionAlg, message string) []byte {
+// encryptMessage encrypts message with the keys of the message, or of its author for the asymmetric algorithms
+func encryptMessage(alg db.EncryptionAlg, message string, keys *userKeys, key *messageKey) []byte {
 
 	switch alg {
 	case db.Rsa:
-		encryptedMessage, _ := rsaCipher.Encrypt([]byte(message))
-		return []byte(fmt.Sprint(encryptedMessage))
+		rsaKey, err := rsa.ParsePKCS8PrivateKey(keys.RSAKey)
+		if err != nil {
+			return nil
+		}
+		encryptedMessage, _ := envelope.Seal(envelope.RSARecipient(&rsaKey.PublicKey), []byte(message))
+		return encryptedMessage
 	case db.Caesar:
+		caesarCipher := Caesar.MakeCaesarCipher()
+		caesarCipher.SetKey(key.Shift)
 		encryptedMessage := caesarCipher.Encrypt(message)
 		return []byte(encryptedMessage)
 	case db.CaesarPerm:
+		caesarPermutationCipher := makeCaesarPermutationCipher(key)
 		encryptedMessage := caesarPermutationCipher.Encrypt(message)
 		return []byte(encryptedMessage)
 	case db.Playfair:
+		playfairCipher := Playfair.MakePlayfairCipher(key.Keyword)
 		encryptedMessage := playfairCipher.Encrypt(message)
 		return []byte(encryptedMessage)
 	case db.Vigener:
+		vigenereCipher := Vigener.MakeVigenereCipher(key.Keyword)
 		encryptedMessage := vigenereCipher.Encrypt(message)
 		return []byte(encryptedMessage)
 	case db.Blowfish:
-		encryptedMessage, _ := blowfishCipher.Encrypt([]byte(message))
+		encryptedMessage, _ := encryptBlowfish(key.Key, []byte(message))
 		return encryptedMessage
 	case db.OneTimePad:
+		otpCipher, err := oneTimePad.NewPad(key.Key, len(key.Key), 1)
+		if err != nil {
+			return nil
+		}
 		encryptedMessage, _ := otpCipher.Encrypt([]byte(message))
 		return encryptedMessage
+	case db.ElGamal:
+		if keys.ElGamalKey == nil {
+			return nil
+		}
+		encryptedMessage, _ := encryptElGamal(elgamal.ElGamal{PrivateKey: *keys.ElGamalKey}, []byte(message))
+		return encryptedMessage
 
 	}
 	return nil
 }
 
-func MakeCiphers() {
-	caesarCipher = Caesar.MakeCaesarCipher()
-	caesarCipher.SetKey(4)
-
-	caesarPermutationCipher = CaesarPermutation.MakeCaesarPermutationCipher()
-	caesarPermutationCipher.SetKey(0)
-
-	vigenereCipher = Vigener.MakeVigenereCipher("thisisasamplekey")
-
-	playfairCipher = Playfair.MakePlayfairCipher("thisisasamplekey")
+func makeCaesarPermutationCipher(key *messageKey) CaesarPermutation.CaesarPermutationCipher {
+	caesarPermutationCipher := CaesarPermutation.MakeCaesarPermutationCipher()
+	caesarPermutationCipher.SetKey(key.Shift)
+	caesarPermutationCipher.SetAlphabet([]rune(key.Alphabet))
+	return caesarPermutationCipher
+}
 
-	//the 8 bytes long key for Blowfish
-	keyBlowfish := []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xAB, 0xCD, 0xEF}
-	//It returns a Blowfish object
-	var err error
-	blowfishCipher, err = blowfish.NewBlowfish(keyBlowfish)
+// encryptBlowfish encrypts message in CTR mode under a random nonce, which is prepended to the ciphertext
+func encryptBlowfish(key, message []byte) ([]byte, error) {
+	blowfishCipher, err := blowfish.NewBlowfish(key)
 	if err != nil {
-		log.Panicf("blowfishCipher error( %d bytes) = %s", len(keyBlowfish), err)
+		return nil, err
 	}
-
-	//initializing the list of 16*2048 bytes for the otp key. Each page of pad the  will be of size 16 bytes, in total 2048 pages
-	keyOTP := make([]byte, 16*2048)
-	//Generating random bits
-	rand.Read(keyOTP)
-	//It returns a new OTP object , and sets the page to 1
-	otpCipher, err = oneTimePad.NewPad(keyOTP, 16, 1)
+	nonce := make([]byte, blowfish.BlockSize)
+	if _, err := crand.Read(nonce); err != nil {
+		return nil, err
+	}
+	ctr, err := modes.NewCTR(blowfishCipher, nonce)
 	if err != nil {
-		fmt.Printf("%s", err)
+		return nil, err
+	}
+	encrypted, err := ctr.Encrypt(message)
+	if err != nil {
+		return nil, err
+	}
+	return append(nonce, encrypted...), nil
+}
 
+// decryptBlowfish reverses encryptBlowfish
+func decryptBlowfish(key, encrypted []byte) ([]byte, error) {
+	if len(encrypted) < blowfish.BlockSize {
+		return nil, ErrEncryption
+	}
+	blowfishCipher, err := blowfish.NewBlowfish(key)
+	if err != nil {
+		return nil, err
+	}
+	ctr, err := modes.NewCTR(blowfishCipher, encrypted[:blowfish.BlockSize])
+	if err != nil {
+		return nil, err
 	}
+	return ctr.Decrypt(encrypted[blowfish.BlockSize:])
+}
 
-	rsaCipher, _ = rsa.NewRSA()
+// encryptElGamal encrypts message and stores every number of the ciphertext on as many bytes as the modulus
+func encryptElGamal(elgamalCipher elgamal.ElGamal, message []byte) ([]byte, error) {
+	encNums, err := elgamalCipher.Encrypt(message)
+	if err != nil {
+		return nil, err
+	}
+	size := (elgamalCipher.P.BitLen() + 7) / 8
+	encrypted := make([]byte, len(encNums)*size)
+	for i, num := range encNums {
+		num.FillBytes(encrypted[i*size : (i+1)*size])
+	}
+	return encrypted, nil
+}
 
+// decryptElGamal reverses encryptElGamal
+func decryptElGamal(elgamalCipher elgamal.ElGamal, encrypted []byte) ([]byte, error) {
+	size := (elgamalCipher.P.BitLen() + 7) / 8
+	if len(encrypted)%size != 0 {
+		return nil, ErrEncryption
+	}
+	encNums := make([]*big.Int, 0, len(encrypted)/size)
+	for i := 0; i < len(encrypted); i += size {
+		encNums = append(encNums, new(big.Int).SetBytes(encrypted[i:i+size]))
+	}
+	return elgamalCipher.Decrypt(encNums)
 }
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"github.com/EliriaT/CS-Labs/api/db"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/envelope"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/rsa"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"time"
+)
+
+var (
+	ErrShareWithAuthor  = errors.New("A message can not be shared with its author")
+	ErrUnknownRecipient = errors.New("Unknown recipient")
+	ErrNoGrant          = errors.New("The message is not shared with this user")
+	ErrNotShareable     = errors.New("ElGamal messages can not be shared")
+)
+
+// SharedMessageItem is a decrypted message shared with the user by its author. As for MessageItem, a message which
+// does not decrypt is listed with an empty Plaintext and the reason in Error.
+type SharedMessageItem struct {
+	Id        uuid.UUID `json:"id"`
+	Author    string    `json:"author"`
+	Algorithm string    `json:"algorithm"`
+	CreatedAt time.Time `json:"created_at"`
+	SharedAt  time.Time `json:"shared_at"`
+	Plaintext string    `json:"plaintext"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// ShareMessage gives recipient read access to a message of username, sealing the keys of the message for recipient.
+// Sharing a message again with the same recipient replaces the grant.
+func (m *messageService) ShareMessage(username string, messageID uuid.UUID, recipientName string) (db.Grant, error) {
+	m.sharesMu.Lock()
+	defer m.sharesMu.Unlock()
+	author, message, err := m.getOwnMessage(username, messageID)
+	if err != nil {
+		return db.Grant{}, err
+	}
+	if recipientName == username {
+		return db.Grant{}, ErrShareWithAuthor
+	}
+	recipient, err := m.db.GetUser(recipientName)
+	if err != nil {
+		return db.Grant{}, ErrUnknownRecipient
+	}
+
+	key, err := m.sharedKeyOf(author, message)
+	if err != nil {
+		return db.Grant{}, err
+	}
+	wrappedKey, err := m.keyring.sealSharedKey(recipient, key)
+	if err != nil {
+		return db.Grant{}, err
+	}
+
+	grant := db.Grant{
+		MessageId:  messageID,
+		Recipient:  recipient.Username,
+		CreatedAt:  time.Now().UTC(),
+		WrappedKey: wrappedKey,
+	}
+	if err := m.db.StoreGrant(&grant); err != nil {
+		return db.Grant{}, err
+	}
+	return grant, nil
+}
+
+// RevokeShare removes the read access of recipient on a message of username
+func (m *messageService) RevokeShare(username string, messageID uuid.UUID, recipient string) error {
+	m.sharesMu.Lock()
+	defer m.sharesMu.Unlock()
+	if _, _, err := m.getOwnMessage(username, messageID); err != nil {
+		return err
+	}
+	err := m.db.DeleteGrant(messageID, recipient)
+	if errors.Is(err, db.ErrNotFound) {
+		return ErrNoGrant
+	}
+	return err
+}
+
+// GetSharesOfMessage returns the grants of a message of username
+func (m *messageService) GetSharesOfMessage(username string, messageID uuid.UUID) ([]db.Grant, error) {
+	if _, _, err := m.getOwnMessage(username, messageID); err != nil {
+		return nil, err
+	}
+	return m.db.GetGrantsOfMessage(messageID)
+}
+
+// GetMessagesSharedWithUser returns the decrypted messages shared with username, in the order they were shared
+func (m *messageService) GetMessagesSharedWithUser(username string) ([]SharedMessageItem, error) {
+	user, err := m.db.GetUser(username)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	grants, err := m.db.GetGrantsOfRecipient(username)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []SharedMessageItem{}
+	for _, grant := range grants {
+		message, err := m.db.GetMessage(grant.MessageId)
+		if errors.Is(err, db.ErrNotFound) {
+			// deleted since the grants were read
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		item := SharedMessageItem{
+			Id:        message.Id,
+			Author:    message.Author,
+			Algorithm: message.EncryptionAlg.String(),
+			CreatedAt: message.CreatedAt,
+			SharedAt:  grant.CreatedAt,
+		}
+		if decrypted := m.decryptSharedMessage(user, grant, message); decrypted != nil {
+			item.Plaintext = string(decrypted)
+		} else {
+			item.Error = ErrEncryption.Error()
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// sharedKeyOf returns what a recipient needs to read message. The RSA messages are envelopes sealed to the public key
+// of their author, so only the data key of the envelope is shared. The ElGamal messages are encrypted to the public key
+// of their author without a data key to share, and can not be shared at all.
+func (m *messageService) sharedKeyOf(author db.User, message db.Message) (*sharedKey, error) {
+	if message.EncryptionAlg == db.ElGamal {
+		return nil, ErrNotShareable
+	}
+	keys, err := m.keyring.userKeys(author)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &sharedKey{MessageId: message.Id}
+	if message.EncryptionAlg == db.Rsa {
+		rsaKey, err := rsa.ParsePKCS8PrivateKey(keys.RSAKey)
+		if err != nil {
+			return nil, ErrKeys
+		}
+		if key.DataKey, err = envelope.OpenDataKey(envelope.RSAIdentity(rsaKey), message.EncryptedMessage); err != nil {
+			return nil, ErrEncryption
+		}
+		return key, nil
+	}
+	if key.Key, err = keys.openMessageKey(message); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// resealGrants seals the keys of message again for every user it is shared with, after its keys changed.
+// The grants which can not be sealed again, such as all of them once the message is encrypted with ElGamal,
+// are returned in dropped. Nothing is written to the store, sharesMu must be held until reshare.
+func (m *messageService) resealGrants(author db.User, message db.Message) (resealed, dropped []db.Grant, err error) {
+	grants, err := m.db.GetGrantsOfMessage(message.Id)
+	if err != nil || len(grants) == 0 {
+		return nil, nil, err
+	}
+
+	key, err := m.sharedKeyOf(author, message)
+	if err == ErrNotShareable {
+		return nil, grants, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, grant := range grants {
+		recipient, err := m.db.GetUser(grant.Recipient)
+		if err == nil {
+			grant.WrappedKey, err = m.keyring.sealSharedKey(recipient, key)
+		}
+		if err != nil {
+			dropped = append(dropped, grant)
+			continue
+		}
+		resealed = append(resealed, grant)
+	}
+	return resealed, dropped, nil
+}
+
+// reshare stores the grants sealed again by resealGrants, once the message is updated, and deletes the others:
+// a grant holding the keys of the old content must not outlive it. sharesMu must be held since resealGrants.
+func (m *messageService) reshare(resealed, dropped []db.Grant) error {
+	var firstErr error
+	for _, grant := range resealed {
+		if err := m.db.StoreGrant(&grant); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			dropped = append(dropped, grant)
+		}
+	}
+	for _, grant := range dropped {
+		if err := m.db.DeleteGrant(grant.MessageId, grant.Recipient); err != nil && !errors.Is(err, db.ErrNotFound) && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// decryptSharedMessage decrypts message with the keys of grant, opened by its recipient
+func (m *messageService) decryptSharedMessage(recipient db.User, grant db.Grant, message db.Message) []byte {
+	key, err := m.keyring.openSharedKey(recipient, grant)
+	if err != nil {
+		return nil
+	}
+	switch message.EncryptionAlg {
+	case db.Rsa:
+		decryptedMessage, _ := envelope.OpenWithDataKey(key.DataKey, message.EncryptedMessage)
+		return decryptedMessage
+	case db.ElGamal:
+		return nil
+	}
+	if key.Key == nil {
+		return nil
+	}
+	return decryptWithKeys(message, nil, key.Key)
+}
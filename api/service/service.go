@@ -2,6 +2,8 @@ package service
 
 import (
 	"github.com/EliriaT/CS-Labs/api/db"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/paillier"
+	"github.com/EliriaT/CS-Labs/classicCipher/cryptanalysis"
 	"github.com/google/uuid"
 	"github.com/pquerna/otp"
 )
@@ -12,14 +14,26 @@ type Service interface {
 	CheckTOTP(username, totp string) (db.User, error)
 	StoreAndEncryptMessage(username string, message string, encryptAlgorithm int) (db.Message, error)
 	GetMessageFromDB(username string, messageID uuid.UUID) (string, error)
-	GetMessagesOfUser(username string) ([]string, error)
+	ListMessages(username string, filter MessageFilter) (MessagePage, error)
+	UpdateMessage(username string, messageID uuid.UUID, message string, encryptAlgorithm int) (db.Message, error)
+	DeleteMessage(username string, messageID uuid.UUID) error
+	ShareMessage(username string, messageID uuid.UUID, recipient string) (db.Grant, error)
+	RevokeShare(username string, messageID uuid.UUID, recipient string) error
+	GetSharesOfMessage(username string, messageID uuid.UUID) ([]db.Grant, error)
+	GetMessagesSharedWithUser(username string) ([]SharedMessageItem, error)
+	BreakCaesar(ciphertext string) (cryptanalysis.CaesarResult, error)
+	TallyPublicKey() []byte
+	AddToTally(ciphertext []byte) (int, error)
+	GetTallyResult() (TallyResult, error)
 }
 
 type ServerService struct {
 	MessageService
 	UserService
+	CryptanalysisService
+	TallyService
 }
 
-func NewServerService(database db.Store) Service {
-	return &ServerService{MessageService: NewMessageService(database), UserService: NewUserService(database)}
+func NewServerService(database db.Store, keyring *Keyring, tallyKey *paillier.PublicKey) Service {
+	return &ServerService{MessageService: NewMessageService(database, keyring), UserService: NewUserService(database, keyring), CryptanalysisService: NewCryptanalysisService(), TallyService: NewTallyService(tallyKey)}
 }
@@ -0,0 +1,117 @@
+package service
+
+import (
+	crand "crypto/rand"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/paillier"
+	"github.com/pkg/errors"
+	"math/big"
+	"os"
+)
+
+var ErrTallyPrivateKey = errors.New("the tally key file holds a private key, the server must only be given the public key")
+
+// TallyResult is the state of the tally: the number of values and their encrypted sum.
+// The sum is never decrypted by the server, the operator holding the tally key decrypts it with DecryptTally.
+type TallyResult struct {
+	Count        int    `json:"count"`
+	EncryptedSum []byte `json:"encrypted_sum"`
+}
+
+// TallyService sums values encrypted with Paillier by the clients. It only holds the public key, so it can add the
+// values while encrypted but can read neither them nor their total.
+type TallyService interface {
+	TallyPublicKey() []byte
+	AddToTally(ciphertext []byte) (int, error)
+	GetTallyResult() (TallyResult, error)
+}
+
+type tallyService struct {
+	key   *paillier.PublicKey
+	tally *paillier.Tally
+}
+
+// TallyPublicKey returns the modulus N, with which clients encrypt their values
+func (t *tallyService) TallyPublicKey() []byte {
+	return t.key.N.Bytes()
+}
+
+// AddToTally adds a value encrypted by the client
+func (t *tallyService) AddToTally(ciphertext []byte) (int, error) {
+	return t.tally.Add(new(big.Int).SetBytes(ciphertext))
+}
+
+func (t *tallyService) GetTallyResult() (TallyResult, error) {
+	encryptedSum, count := t.tally.Sum()
+	return TallyResult{
+		Count:        count,
+		EncryptedSum: encryptedSum.FillBytes(make([]byte, t.key.Size())),
+	}, nil
+}
+
+func NewTallyService(key *paillier.PublicKey) TallyService {
+	return &tallyService{key: key, tally: paillier.NewTally(key)}
+}
+
+// LoadTallyKey reads the public tally key from a PEM file, made by the operator with GenerateTallyKey.
+// The server never creates the key and refuses a private key, which must stay with the operator.
+// The tally itself is kept in memory and starts again from zero when the server restarts.
+func LoadTallyKey(path string) (*paillier.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := paillier.DecodePrivateKeyPEM(data); err == nil {
+		return nil, ErrTallyPrivateKey
+	}
+	return paillier.DecodePublicKeyPEM(data)
+}
+
+// GenerateTallyKey generates a tally key pair for the operator, who keeps the private key at privatePath
+// and gives the public key at publicPath to the server. Existing files are not overwritten.
+func GenerateTallyKey(privatePath, publicPath string) error {
+	key, err := paillier.GenerateKey(crand.Reader, paillier.DefaultKeySize)
+	if err != nil {
+		return err
+	}
+	private, err := paillier.EncodePrivateKeyPEM(key)
+	if err != nil {
+		return err
+	}
+	public, err := paillier.EncodePublicKeyPEM(&key.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	if err := writeNewFile(privatePath, private, 0600); err != nil {
+		return err
+	}
+	return writeNewFile(publicPath, public, 0644)
+}
+
+// writeNewFile writes data to a file which must not exist yet
+func writeNewFile(path string, data []byte, perm os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// DecryptTally decrypts an encrypted sum returned by GetTallyResult with the private key of the PEM file at path,
+// as written by GenerateTallyKey.
+// It is run by the operator outside of the server, which never decrypts the sum.
+func DecryptTally(path string, encryptedSum []byte) (*big.Int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := paillier.DecodePrivateKeyPEM(data)
+	if err != nil {
+		return nil, err
+	}
+	return paillier.Decrypt(key, new(big.Int).SetBytes(encryptedSum))
+}
@@ -0,0 +1,64 @@
+package service_test
+
+import (
+	crand "crypto/rand"
+	"github.com/EliriaT/CS-Labs/api/service"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/paillier"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestTallyKey generates the key pair as the operator does, runs a tally on the public key and decrypts the sum offline
+func TestTallyKey(t *testing.T) {
+	dir := t.TempDir()
+	privatePath, publicPath := filepath.Join(dir, "tally_key.pem"), filepath.Join(dir, "tally_public_key.pem")
+
+	if _, err := service.LoadTallyKey(publicPath); !os.IsNotExist(err) {
+		t.Fatalf("LoadTallyKey of a missing file = %v, want a not exist error", err)
+	}
+	if _, err := os.Stat(publicPath); !os.IsNotExist(err) {
+		t.Fatal("LoadTallyKey created the key file")
+	}
+
+	if err := service.GenerateTallyKey(privatePath, publicPath); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(privatePath); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("the private key file is %v, %v, want mode 0600", info, err)
+	}
+	if err := service.GenerateTallyKey(privatePath, publicPath); !os.IsExist(err) {
+		t.Fatalf("GenerateTallyKey over an existing key = %v, want an exist error", err)
+	}
+
+	if _, err := service.LoadTallyKey(privatePath); err != service.ErrTallyPrivateKey {
+		t.Fatalf("LoadTallyKey of the private key = %v, want ErrTallyPrivateKey", err)
+	}
+	key, err := service.LoadTallyKey(publicPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tally := service.NewTallyService(key)
+	for _, value := range []int64{3, 4, 5} {
+		c, err := paillier.Encrypt(crand.Reader, key, big.NewInt(value))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tally.AddToTally(c.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result, err := tally.GetTallyResult()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := service.DecryptTally(privatePath, result.EncryptedSum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Count != 3 || sum.Int64() != 12 {
+		t.Fatalf("the tally is %v of %d values, want 12 of 3", sum, result.Count)
+	}
+}
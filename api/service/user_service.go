@@ -21,7 +21,8 @@ type UserService interface {
 }
 
 type userService struct {
-	db db.Store
+	db      db.Store
+	keyring *Keyring
 }
 
 func (s *userService) Register(username, password string, choice int) (db.User, *otp.Key, error) {
@@ -50,16 +51,28 @@ func (s *userService) Register(username, password string, choice int) (db.User,
 		AccountName: username,
 	})
 
+	encryptedKeys, err := s.keyring.NewUserKeys(userId, db.CipherChoice(choice))
+	if err != nil {
+		return db.User{}, nil, err
+	}
+
 	user := db.User{
-		Id:         userId,
-		Username:   username,
-		Password:   hashedPassword,
-		Choice:     db.CipherChoice(choice),
-		TOTPSecret: key.Secret(),
+		Id:            userId,
+		Username:      username,
+		Password:      hashedPassword,
+		Choice:        db.CipherChoice(choice),
+		TOTPSecret:    key.Secret(),
+		EncryptedKeys: encryptedKeys,
 	}
 
-	s.db.StoreUser(&user)
-	return user, key, s.db.SetUser(username, user)
+	// the username may have been taken since the check above, StoreUser refuses it atomically
+	if err := s.db.StoreUser(&user); err != nil {
+		if errors.Is(err, db.ErrDuplicateUser) {
+			return db.User{}, nil, ErrDuplicateUsername
+		}
+		return db.User{}, nil, err
+	}
+	return user, key, nil
 }
 
 func (s *userService) Login(username, password string) (db.User, error) {
@@ -86,6 +99,6 @@ func (s *userService) CheckTOTP(username, totpToken string) (db.User, error) {
 	return user, nil
 }
 
-func NewUserService(database db.Store) UserService {
-	return &userService{db: database}
+func NewUserService(database db.Store, keyring *Keyring) UserService {
+	return &userService{db: database, keyring: keyring}
 }
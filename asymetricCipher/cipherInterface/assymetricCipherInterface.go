@@ -1,7 +1,16 @@
 package cipherInterface
 
+import "math/big"
+
 type AssymetricCipher interface {
 	Encrypt(src []byte) ([]int64, error)
 	Decrypt(src []int64) ([]byte, error)
 	Name() string
 }
+
+// BigAssymetricCipher is an AssymetricCipher whose ciphertexts can be larger than an int64, like real-size RSA
+type BigAssymetricCipher interface {
+	Encrypt(src []byte) ([]*big.Int, error)
+	Decrypt(src []*big.Int) ([]byte, error)
+	Name() string
+}
@@ -0,0 +1,149 @@
+package elgamal
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+)
+
+var (
+	ErrMessageTooLarge = errors.New("elgamal: message must be from 1 to Q")
+	ErrDecryption      = errors.New("elgamal: decryption error")
+)
+
+// A PublicKey represents the public part of an ElGamal key, Y = G^X mod P
+type PublicKey struct {
+	Parameters
+	Y *big.Int
+}
+
+// A PrivateKey represents an ElGamal key
+type PrivateKey struct {
+	PublicKey          // public part.
+	X         *big.Int // private exponent
+}
+
+// GenerateKey generates an ElGamal key pair in the group params, with a private exponent in [1, Q-1]
+func GenerateKey(random io.Reader, params *Parameters) (*PrivateKey, error) {
+	x, err := randomInRange(random, bigOne, new(big.Int).Sub(params.Q, bigOne))
+	if err != nil {
+		return nil, err
+	}
+	return &PrivateKey{
+		PublicKey: PublicKey{
+			Parameters: *params,
+			Y:          new(big.Int).Exp(params.G, x, params.P),
+		},
+		X: x,
+	}, nil
+}
+
+// Encrypt encrypts m, which must be in [1, Q], as the pair (G^k, M*Y^k) for a fresh random k,
+// so encrypting the same message twice gives different ciphertexts. M is m mapped into the subgroup of order Q
+// by encode: a message outside of it would show through the Legendre symbol of M*Y^k.
+func Encrypt(random io.Reader, pub *PublicKey, m *big.Int) (c1, c2 *big.Int, err error) {
+	if m.Sign() <= 0 || m.Cmp(pub.Q) > 0 {
+		return nil, nil, ErrMessageTooLarge
+	}
+	k, err := randomInRange(random, bigOne, new(big.Int).Sub(pub.Q, bigOne))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c1 = new(big.Int).Exp(pub.G, k, pub.P)
+	c2 = new(big.Int).Exp(pub.Y, k, pub.P)
+	c2.Mul(c2, encode(&pub.Parameters, m)).Mod(c2, pub.P)
+	return c1, c2, nil
+}
+
+// Decrypt recovers M = c2 * (c1^X)^-1 mod P and decodes m from it
+func Decrypt(priv *PrivateKey, c1, c2 *big.Int) (*big.Int, error) {
+	if c1.Sign() <= 0 || c1.Cmp(priv.P) >= 0 || c2.Sign() <= 0 || c2.Cmp(priv.P) >= 0 {
+		return nil, ErrDecryption
+	}
+	s := new(big.Int).Exp(c1, priv.X, priv.P)
+	if s.ModInverse(s, priv.P) == nil {
+		return nil, ErrDecryption
+	}
+	return decode(&priv.Parameters, s.Mul(s, c2).Mod(s, priv.P)), nil
+}
+
+// encode maps m in [1, Q] to the quadratic residues, the subgroup of order Q: P is a safe prime, so -1 is not a residue
+// and exactly one of m and P-m is one
+func encode(params *Parameters, m *big.Int) *big.Int {
+	if big.Jacobi(m, params.P) == 1 {
+		return new(big.Int).Set(m)
+	}
+	return new(big.Int).Sub(params.P, m)
+}
+
+// decode reverses encode, taking back the one of M and P-M which is at most Q
+func decode(params *Parameters, encoded *big.Int) *big.Int {
+	if encoded.Cmp(params.Q) > 0 {
+		return encoded.Sub(params.P, encoded)
+	}
+	return encoded
+}
+
+type ElGamal struct {
+	PrivateKey
+}
+
+// chunkSize is the number of message bytes encrypted in each pair: the whole bytes below the top bit of Q,
+// so that every chunk is smaller than Q, minus the 0x01 marker that keeps the leading zero bytes of a chunk
+func (e ElGamal) chunkSize() int {
+	return (e.Q.BitLen()-1)/8 - 1
+}
+
+// Encrypt splits src in chunks smaller than the modulus and returns the pairs (c1, c2) of every chunk, one after the other
+func (e ElGamal) Encrypt(src []byte) ([]*big.Int, error) {
+	size := e.chunkSize()
+	var encNums []*big.Int
+	for i := 0; i < len(src); i += size {
+		end := i + size
+		if end > len(src) {
+			end = len(src)
+		}
+		m := new(big.Int).SetBytes(append([]byte{1}, src[i:end]...))
+		c1, c2, err := Encrypt(rand.Reader, &e.PublicKey, m)
+		if err != nil {
+			return nil, err
+		}
+		encNums = append(encNums, c1, c2)
+	}
+	return encNums, nil
+}
+
+func (e ElGamal) Decrypt(src []*big.Int) ([]byte, error) {
+	if len(src)%2 != 0 {
+		return nil, ErrDecryption
+	}
+
+	var decBytes []byte
+	for i := 0; i < len(src); i += 2 {
+		m, err := Decrypt(&e.PrivateKey, src[i], src[i+1])
+		if err != nil {
+			return nil, err
+		}
+		chunk := m.Bytes()
+		if len(chunk) == 0 || chunk[0] != 1 {
+			return nil, ErrDecryption
+		}
+		decBytes = append(decBytes, chunk[1:]...)
+	}
+	return decBytes, nil
+}
+
+func (e ElGamal) Name() string {
+	return "ElGamal"
+}
+
+// NewElGamal creates and returns an ElGamal cipher with a new key in the 2048-bit RFC 3526 group.
+func NewElGamal() (ElGamal, error) {
+	key, err := GenerateKey(rand.Reader, Group14())
+	if err != nil {
+		return ElGamal{}, err
+	}
+	return ElGamal{PrivateKey: *key}, nil
+}
@@ -0,0 +1,162 @@
+package elgamal_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/elgamal"
+	"math/big"
+	"sync"
+	"testing"
+)
+
+var (
+	smallOnce   sync.Once
+	smallGroups []*elgamal.Parameters
+)
+
+// groups returns the 2048-bit RFC 3526 group and small generated groups, whose chunks are a few bytes long.
+// The 129-bit group has a Q of 128 bits, a whole number of bytes.
+func groups(t *testing.T) []*elgamal.Parameters {
+	t.Helper()
+	smallOnce.Do(func() {
+		for _, bits := range []int{128, 129} {
+			params, err := elgamal.GenerateParameters(rand.Reader, bits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			smallGroups = append(smallGroups, params)
+		}
+	})
+	return append([]*elgamal.Parameters{elgamal.Group14()}, smallGroups...)
+}
+
+func newCipher(t *testing.T, params *elgamal.Parameters) elgamal.ElGamal {
+	t.Helper()
+	key, err := elgamal.GenerateKey(rand.Reader, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return elgamal.ElGamal{PrivateKey: *key}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	long := make([]byte, 1000)
+	rand.Read(long)
+	messages := [][]byte{
+		[]byte("hello"),
+		{0, 0, 0, 1},
+		make([]byte, 40),
+		{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		long,
+	}
+
+	for _, params := range groups(t) {
+		cipher := newCipher(t, params)
+		for _, message := range messages {
+			encrypted, err := cipher.Encrypt(message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, value := range encrypted {
+				// every value is in the subgroup of order Q, so none of them tells anything through its Legendre symbol
+				if new(big.Int).Exp(value, params.Q, params.P).Cmp(big.NewInt(1)) != 0 {
+					t.Fatalf("%d-bit group: value %d of the ciphertext is outside the subgroup", params.P.BitLen(), i)
+				}
+			}
+			decrypted, err := cipher.Decrypt(encrypted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decrypted, message) {
+				t.Fatalf("%d-bit group: Decrypt = %x, want %x", params.P.BitLen(), decrypted, message)
+			}
+		}
+	}
+}
+
+// TestMessageRange encrypts the bounds of the message range, a residue and a non-residue
+func TestMessageRange(t *testing.T) {
+	for _, params := range groups(t) {
+		key, err := elgamal.GenerateKey(rand.Reader, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonResidue := new(big.Int).Sub(params.P, params.G)
+		nonResidue.Rsh(nonResidue, 1)
+		for big.Jacobi(nonResidue, params.P) == 1 {
+			nonResidue.Sub(nonResidue, big.NewInt(1))
+		}
+
+		for _, m := range []*big.Int{big.NewInt(1), big.NewInt(4), nonResidue, params.Q} {
+			c1, c2, err := elgamal.Encrypt(rand.Reader, &key.PublicKey, m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if big.Jacobi(c2, params.P) != 1 {
+				t.Fatalf("%d-bit group: c2 of %v is not a quadratic residue", params.P.BitLen(), m)
+			}
+			got, err := elgamal.Decrypt(key, c1, c2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Cmp(m) != 0 {
+				t.Fatalf("%d-bit group: Decrypt = %v, want %v", params.P.BitLen(), got, m)
+			}
+		}
+
+		for _, m := range []*big.Int{big.NewInt(0), new(big.Int).Add(params.Q, big.NewInt(1)), params.P} {
+			if _, _, err := elgamal.Encrypt(rand.Reader, &key.PublicKey, m); err != elgamal.ErrMessageTooLarge {
+				t.Fatalf("Encrypt(%v) = %v, want ErrMessageTooLarge", m, err)
+			}
+		}
+	}
+}
+
+func TestDecryptInvalid(t *testing.T) {
+	cipher := newCipher(t, groups(t)[1])
+	encrypted, err := cipher.Encrypt([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cipher.Decrypt(encrypted[:1]); err != elgamal.ErrDecryption {
+		t.Fatalf("Decrypt of an odd number of values = %v, want ErrDecryption", err)
+	}
+	if _, err := cipher.Decrypt([]*big.Int{big.NewInt(0), encrypted[1]}); err != elgamal.ErrDecryption {
+		t.Fatalf("Decrypt with c1 = 0: %v, want ErrDecryption", err)
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	for _, params := range groups(t) {
+		cipher := newCipher(t, params)
+		digest := sha256.Sum256([]byte("the message"))
+
+		r, s, err := cipher.Sign(digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cipher.Verify(digest[:], r, s); err != nil {
+			t.Fatalf("%d-bit group: %v", params.P.BitLen(), err)
+		}
+
+		tampered := sha256.Sum256([]byte("the message!"))
+		if err := cipher.Verify(tampered[:], r, s); err != elgamal.ErrVerification {
+			t.Fatalf("%d-bit group: the signature verified another message: %v", params.P.BitLen(), err)
+		}
+		if err := cipher.Verify(digest[:], new(big.Int).Add(r, big.NewInt(1)), s); err != elgamal.ErrVerification {
+			t.Fatalf("%d-bit group: a tampered r verified: %v", params.P.BitLen(), err)
+		}
+		if err := cipher.Verify(digest[:], r, new(big.Int).Add(s, big.NewInt(1))); err != elgamal.ErrVerification {
+			t.Fatalf("%d-bit group: a tampered s verified: %v", params.P.BitLen(), err)
+		}
+		if err := cipher.Verify(digest[:], big.NewInt(0), s); err != elgamal.ErrVerification {
+			t.Fatalf("%d-bit group: r = 0 verified: %v", params.P.BitLen(), err)
+		}
+
+		other := newCipher(t, params)
+		if err := other.Verify(digest[:], r, s); err != elgamal.ErrVerification {
+			t.Fatalf("%d-bit group: the signature verified with another key: %v", params.P.BitLen(), err)
+		}
+	}
+}
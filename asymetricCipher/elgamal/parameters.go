@@ -0,0 +1,74 @@
+package elgamal
+
+import (
+	"errors"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/keyexchange"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/rsa"
+	"io"
+	"math/big"
+)
+
+// Parameters describe the group of an ElGamal key: P is a safe prime, P = 2Q+1,
+// and G generates the subgroup of order Q of the integers modulo P.
+type Parameters struct {
+	P *big.Int
+	Q *big.Int
+	G *big.Int
+}
+
+// Group14 returns the 2048-bit safe-prime group of RFC 3526, the one used for Diffie-Hellman
+func Group14() *Parameters {
+	group := keyexchange.Group14()
+	return &Parameters{P: group.P, Q: group.Q, G: group.G}
+}
+
+// GenerateParameters finds a new safe prime of bits bits and a generator of its subgroup of prime order.
+// Safe primes are rare, so this can take minutes for 2048 bits; Group14 should be preferred.
+func GenerateParameters(random io.Reader, bits int) (*Parameters, error) {
+	if bits < 64 {
+		return nil, errors.New("elgamal: group size must be at least 64 bits")
+	}
+
+	p := new(big.Int)
+	for {
+		q, err := rsa.GeneratePrime(random, bits-1)
+		if err != nil {
+			return nil, err
+		}
+		p.Lsh(q, 1).Add(p, bigOne)
+		if !rsa.IsProbablePrime(p, 40, random) {
+			continue
+		}
+
+		// Squares have an order dividing Q, so any square other than 1 generates the subgroup of order Q
+		for {
+			h, err := randomInRange(random, bigTwo, new(big.Int).Sub(p, bigTwo))
+			if err != nil {
+				return nil, err
+			}
+			g := h.Exp(h, bigTwo, p)
+			if g.Cmp(bigOne) != 0 {
+				return &Parameters{P: p, Q: q, G: g}, nil
+			}
+		}
+	}
+}
+
+var (
+	bigOne = big.NewInt(1)
+	bigTwo = big.NewInt(2)
+)
+
+// randomInRange returns a uniform random number in [min, max]
+func randomInRange(random io.Reader, min, max *big.Int) (*big.Int, error) {
+	span := new(big.Int).Sub(max, min)
+	span.Add(span, bigOne)
+	buf := make([]byte, (span.BitLen()+7)/8+8)
+	if _, err := io.ReadFull(random, buf); err != nil {
+		return nil, err
+	}
+	// The 64 extra random bits make the modulo bias negligible
+	n := new(big.Int).SetBytes(buf)
+	n.Mod(n, span)
+	return n.Add(n, min), nil
+}
@@ -0,0 +1,68 @@
+package elgamal
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// ErrVerification is returned for every signature that does not verify
+var ErrVerification = errors.New("elgamal: verification error")
+
+// Sign computes the ElGamal signature (r, s) of digest: r = G^k mod P and s = (H - X*r) * k^-1 mod (P-1),
+// H being the digest as a number and k a fresh random value co-prime to P-1
+func Sign(random io.Reader, priv *PrivateKey, digest []byte) (r, s *big.Int, err error) {
+	pMinusOne := new(big.Int).Sub(priv.P, bigOne)
+	h := new(big.Int).SetBytes(digest)
+	h.Mod(h, pMinusOne)
+
+	for {
+		k, err := randomInRange(random, bigTwo, new(big.Int).Sub(pMinusOne, bigOne))
+		if err != nil {
+			return nil, nil, err
+		}
+		kInv := new(big.Int).ModInverse(k, pMinusOne)
+		if kInv == nil {
+			continue
+		}
+
+		r = new(big.Int).Exp(priv.G, k, priv.P)
+		s = new(big.Int).Mul(priv.X, r)
+		s.Sub(h, s)
+		s.Mul(s, kInv)
+		s.Mod(s, pMinusOne)
+		if s.Sign() != 0 {
+			return r, s, nil
+		}
+	}
+}
+
+// Verify checks that G^H = Y^r * r^s mod P. It returns nil if the signature is valid.
+func Verify(pub *PublicKey, digest []byte, r, s *big.Int) error {
+	pMinusOne := new(big.Int).Sub(pub.P, bigOne)
+	if r.Sign() <= 0 || r.Cmp(pub.P) >= 0 || s.Sign() <= 0 || s.Cmp(pMinusOne) >= 0 {
+		return ErrVerification
+	}
+
+	h := new(big.Int).SetBytes(digest)
+	h.Mod(h, pMinusOne)
+
+	left := new(big.Int).Exp(pub.G, h, pub.P)
+	right := new(big.Int).Exp(pub.Y, r, pub.P)
+	right.Mul(right, new(big.Int).Exp(r, s, pub.P)).Mod(right, pub.P)
+	if left.Cmp(right) != 0 {
+		return ErrVerification
+	}
+	return nil
+}
+
+// Sign signs digest with the cipher's private key
+func (e ElGamal) Sign(digest []byte) (r, s *big.Int, err error) {
+	return Sign(rand.Reader, &e.PrivateKey, digest)
+}
+
+// Verify checks a signature made by Sign
+func (e ElGamal) Verify(digest []byte, r, s *big.Int) error {
+	return Verify(&e.PublicKey, digest, r, s)
+}
@@ -0,0 +1,124 @@
+// Package envelope implements hybrid encryption: the message is encrypted with an authenticated symmetric cipher
+// under a random data key, and the data key is wrapped with the public key of the recipient.
+// The result is a self-describing envelope which names its version and algorithms, so messages of any size
+// can be sent to RSA and ECDH keys.
+package envelope
+
+import (
+	"crypto/rand"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/keyexchange"
+	"github.com/EliriaT/CS-Labs/streamBlockCipher/blowfish"
+	"github.com/EliriaT/CS-Labs/streamBlockCipher/modes"
+	"io"
+)
+
+// cipherInfo is the HKDF info of the keys of the payload cipher
+const cipherInfo = "CS-Labs envelope v1 Blowfish-CTR-HMAC-SHA256"
+
+const (
+	blowfishKeySize = 16
+	macKeySize      = 32
+)
+
+// Seal encrypts plaintext for recipient with Blowfish-CTR-HMAC-SHA256 and returns the envelope
+func Seal(recipient Recipient, plaintext []byte) ([]byte, error) {
+	header := Header{Version: Version, Wrap: recipient.Algorithm(), Cipher: BlowfishCTRHMAC}.marshal()
+	dataKey, wrappedKey, err := recipient.wrapKey(rand.Reader, header)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newCipher(BlowfishCTRHMAC, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	e := &envelope{wrappedKey: wrappedKey, nonce: nonce}
+	e.aad = appendField(appendField(header, wrappedKey), nonce)
+	e.ciphertext = aead.Seal(nil, nonce, plaintext, e.aad)
+	return e.marshal(), nil
+}
+
+// Open checks and decrypts an envelope sealed for identity
+func Open(identity Identity, data []byte) ([]byte, error) {
+	e, dataKey, err := unwrap(identity, data)
+	if err != nil {
+		return nil, err
+	}
+	return e.decrypt(dataKey)
+}
+
+// OpenDataKey returns the data key of an envelope sealed for identity, after checking that it decrypts the payload.
+// The data key only opens this envelope: sealed again for another recipient, it lets them read the envelope with
+// OpenWithDataKey, without the payload being encrypted again nor the private key of identity being shared.
+func OpenDataKey(identity Identity, data []byte) ([]byte, error) {
+	e, dataKey, err := unwrap(identity, data)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := e.decrypt(dataKey); err != nil {
+		return nil, err
+	}
+	return dataKey, nil
+}
+
+// OpenWithDataKey checks and decrypts an envelope with its data key, as returned by OpenDataKey
+func OpenWithDataKey(dataKey, data []byte) ([]byte, error) {
+	if len(dataKey) != dataKeySize {
+		return nil, ErrWrongKey
+	}
+	e, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return e.decrypt(dataKey)
+}
+
+// unwrap parses an envelope and recovers its data key with identity
+func unwrap(identity Identity, data []byte) (*envelope, []byte, error) {
+	e, err := parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if e.Wrap != identity.Algorithm() {
+		return nil, nil, ErrWrongKey
+	}
+
+	dataKey, err := identity.unwrapKey(e.wrappedKey, e.Header.marshal())
+	if err != nil {
+		return nil, nil, err
+	}
+	return e, dataKey, nil
+}
+
+// decrypt checks and decrypts the payload with the data key
+func (e *envelope) decrypt(dataKey []byte) ([]byte, error) {
+	aead, err := newCipher(e.Cipher, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.nonce) != aead.NonceSize() {
+		return nil, ErrFormat
+	}
+	return aead.Open(nil, e.nonce, e.ciphertext, e.aad)
+}
+
+// newCipher derives the keys of the payload cipher from the data key
+func newCipher(algorithm CipherAlgorithm, dataKey []byte) (*modes.CTRHMAC, error) {
+	if algorithm != BlowfishCTRHMAC {
+		return nil, ErrUnsupported
+	}
+	keys, err := keyexchange.DeriveKey(dataKey, nil, []byte(cipherInfo), blowfishKeySize+macKeySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := blowfish.NewBlowfish(keys[:blowfishKeySize])
+	if err != nil {
+		return nil, err
+	}
+	return modes.NewCTRHMAC(block, keys[blowfishKeySize:])
+}
@@ -0,0 +1,84 @@
+package envelope_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/envelope"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/keyexchange"
+	"testing"
+)
+
+func newIdentity(t *testing.T) (envelope.Recipient, envelope.Identity) {
+	t.Helper()
+	key, err := keyexchange.GenerateKey(keyexchange.X25519, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient, err := envelope.ECDHRecipient(keyexchange.X25519, key.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := envelope.ECDHIdentity(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return recipient, identity
+}
+
+func TestSealOpen(t *testing.T) {
+	recipient, identity := newIdentity(t)
+	_, other := newIdentity(t)
+	plaintext := []byte("attack at dawn")
+
+	sealed, err := envelope.Seal(recipient, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened, err := envelope.Open(identity, sealed)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Fatalf("Open = %q, %v, want %q", opened, err, plaintext)
+	}
+	if _, err := envelope.Open(other, sealed); err == nil {
+		t.Fatal("an envelope was opened by another key")
+	}
+
+	sealed[len(sealed)-1] ^= 1
+	if _, err := envelope.Open(identity, sealed); err == nil {
+		t.Fatal("a tampered envelope was opened")
+	}
+}
+
+// TestDataKey checks that the data key of an envelope opens that envelope and no other
+func TestDataKey(t *testing.T) {
+	recipient, identity := newIdentity(t)
+	plaintext := []byte("attack at dawn")
+
+	sealed, err := envelope.Seal(recipient, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataKey, err := envelope.OpenDataKey(identity, sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened, err := envelope.OpenWithDataKey(dataKey, sealed)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Fatalf("OpenWithDataKey = %q, %v, want %q", opened, err, plaintext)
+	}
+
+	another, err := envelope.Seal(recipient, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := envelope.OpenWithDataKey(dataKey, another); err == nil {
+		t.Fatal("the data key of an envelope opened another one")
+	}
+	if _, err := envelope.OpenWithDataKey(dataKey[1:], sealed); err != envelope.ErrWrongKey {
+		t.Fatalf("OpenWithDataKey with a short key = %v, want ErrWrongKey", err)
+	}
+
+	sealed[len(sealed)-1] ^= 1
+	if _, err := envelope.OpenDataKey(identity, sealed); err == nil {
+		t.Fatal("the data key of a tampered envelope was returned")
+	}
+}
@@ -0,0 +1,158 @@
+package envelope
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// An envelope is laid out as:
+//
+//	magic "CSEV" | version (1 byte) | wrap algorithm (1 byte) | cipher algorithm (1 byte)
+//	| wrapped key | nonce | ciphertext
+//
+// each of the last three fields being prefixed with its length as a 4-byte big-endian number.
+// Everything before the ciphertext is authenticated as the additional data of the cipher.
+const (
+	magic = "CSEV"
+	// Version is the version of the format written by Seal
+	Version = 1
+
+	headerSize = len(magic) + 3
+	// maxFieldSize bounds the wrapped key and the nonce, which are small
+	maxFieldSize = 1024
+)
+
+var (
+	ErrFormat      = errors.New("envelope: malformed envelope")
+	ErrVersion     = errors.New("envelope: unsupported version")
+	ErrUnsupported = errors.New("envelope: unsupported algorithm")
+)
+
+// A WrapAlgorithm is the way the data key is protected for the recipient
+type WrapAlgorithm byte
+
+const (
+	// RSAOAEP encrypts the data key with RSA-OAEP and SHA-256
+	RSAOAEP WrapAlgorithm = iota + 1
+	// X25519 derives the data key from an ephemeral X25519 key agreement with HKDF-SHA256
+	X25519
+	// P256 derives the data key from an ephemeral P-256 key agreement with HKDF-SHA256
+	P256
+)
+
+func (w WrapAlgorithm) String() string {
+	switch w {
+	case RSAOAEP:
+		return "RSA-OAEP-SHA256"
+	case X25519:
+		return "X25519-HKDF-SHA256"
+	case P256:
+		return "P256-HKDF-SHA256"
+	}
+	return fmt.Sprintf("WrapAlgorithm(%d)", byte(w))
+}
+
+// A CipherAlgorithm is the authenticated cipher of the payload
+type CipherAlgorithm byte
+
+const (
+	// BlowfishCTRHMAC is Blowfish with a 128-bit key in CTR mode with HMAC-SHA256
+	BlowfishCTRHMAC CipherAlgorithm = iota + 1
+)
+
+func (c CipherAlgorithm) String() string {
+	switch c {
+	case BlowfishCTRHMAC:
+		return "Blowfish-CTR-HMAC-SHA256"
+	}
+	return fmt.Sprintf("CipherAlgorithm(%d)", byte(c))
+}
+
+// Header describes an envelope without opening it
+type Header struct {
+	Version byte
+	Wrap    WrapAlgorithm
+	Cipher  CipherAlgorithm
+}
+
+// envelope is a parsed envelope. aad is the part of the raw envelope that is authenticated along with the ciphertext.
+type envelope struct {
+	Header
+	wrappedKey []byte
+	nonce      []byte
+	ciphertext []byte
+	aad        []byte
+}
+
+// IsEnvelope reports whether data starts like an envelope
+func IsEnvelope(data []byte) bool {
+	return len(data) >= len(magic) && string(data[:len(magic)]) == magic
+}
+
+// ParseHeader reads the version and the algorithms of an envelope
+func ParseHeader(data []byte) (Header, error) {
+	if len(data) < headerSize || !IsEnvelope(data) {
+		return Header{}, ErrFormat
+	}
+	header := Header{Version: data[4], Wrap: WrapAlgorithm(data[5]), Cipher: CipherAlgorithm(data[6])}
+	if header.Version != Version {
+		return Header{}, fmt.Errorf("%w: %d", ErrVersion, header.Version)
+	}
+	return header, nil
+}
+
+func (h Header) marshal() []byte {
+	return append([]byte(magic), h.Version, byte(h.Wrap), byte(h.Cipher))
+}
+
+func (e *envelope) marshal() []byte {
+	out := e.aad
+	out = appendField(out, e.ciphertext)
+	return out
+}
+
+// parse splits an envelope into its fields
+func parse(data []byte) (*envelope, error) {
+	header, err := ParseHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	rest := data[headerSize:]
+	wrappedKey, rest, err := readField(rest, maxFieldSize)
+	if err != nil {
+		return nil, err
+	}
+	nonce, rest, err := readField(rest, maxFieldSize)
+	if err != nil {
+		return nil, err
+	}
+	aad := data[:len(data)-len(rest)]
+	ciphertext, rest, err := readField(rest, len(rest))
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, ErrFormat
+	}
+
+	return &envelope{Header: header, wrappedKey: wrappedKey, nonce: nonce, ciphertext: ciphertext, aad: aad}, nil
+}
+
+func appendField(dst, field []byte) []byte {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(field)))
+	return append(append(dst, length[:]...), field...)
+}
+
+func readField(data []byte, maxSize int) (field, rest []byte, err error) {
+	if len(data) < 4 {
+		return nil, nil, ErrFormat
+	}
+	length := binary.BigEndian.Uint32(data)
+	if uint64(length) > uint64(maxSize) || uint64(length) > uint64(len(data)-4) {
+		return nil, nil, ErrFormat
+	}
+	return data[4 : 4+length], data[4+length:], nil
+}
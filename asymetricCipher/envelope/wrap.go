@@ -0,0 +1,152 @@
+package envelope
+
+import (
+	"crypto/sha256"
+	"errors"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/keyexchange"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/rsa"
+	"io"
+)
+
+// dataKeySize is the size of the random data key, from which the keys of the cipher are derived
+const dataKeySize = 32
+
+// ErrWrongKey is returned when the envelope was not sealed for the given identity
+var ErrWrongKey = errors.New("envelope: the envelope was not sealed for this key")
+
+// A Recipient is the public key an envelope is sealed for
+type Recipient interface {
+	Algorithm() WrapAlgorithm
+	// wrapKey returns a new data key and the bytes from which the recipient can recover it.
+	// header is bound to the wrapped key so that the algorithms can not be swapped.
+	wrapKey(random io.Reader, header []byte) (dataKey, wrappedKey []byte, err error)
+}
+
+// An Identity is the private key that opens the envelopes sealed for its Recipient
+type Identity interface {
+	Algorithm() WrapAlgorithm
+	unwrapKey(wrappedKey, header []byte) (dataKey []byte, err error)
+}
+
+type rsaRecipient struct {
+	pub *rsa.PublicKey
+}
+
+// RSARecipient returns the recipient of an RSA public key. The data key is encrypted with RSA-OAEP and SHA-256.
+func RSARecipient(pub *rsa.PublicKey) Recipient {
+	return rsaRecipient{pub: pub}
+}
+
+func (r rsaRecipient) Algorithm() WrapAlgorithm {
+	return RSAOAEP
+}
+
+func (r rsaRecipient) wrapKey(random io.Reader, header []byte) (dataKey, wrappedKey []byte, err error) {
+	dataKey = make([]byte, dataKeySize)
+	if _, err := io.ReadFull(random, dataKey); err != nil {
+		return nil, nil, err
+	}
+	// the header is the OAEP label
+	wrappedKey, err = rsa.EncryptOAEP(sha256.New(), random, r.pub, dataKey, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	return dataKey, wrappedKey, nil
+}
+
+type rsaIdentity struct {
+	priv *rsa.PrivateKey
+}
+
+// RSAIdentity returns the identity of an RSA private key
+func RSAIdentity(priv *rsa.PrivateKey) Identity {
+	return rsaIdentity{priv: priv}
+}
+
+func (r rsaIdentity) Algorithm() WrapAlgorithm {
+	return RSAOAEP
+}
+
+func (r rsaIdentity) unwrapKey(wrappedKey, header []byte) ([]byte, error) {
+	dataKey, err := rsa.DecryptOAEP(sha256.New(), r.priv, wrappedKey, header)
+	if err != nil || len(dataKey) != dataKeySize {
+		return nil, ErrWrongKey
+	}
+	return dataKey, nil
+}
+
+// ecdhInfo is the HKDF info of the data keys derived from a key agreement
+const ecdhInfo = "CS-Labs envelope v1 ECDH data key"
+
+type ecdhRecipient struct {
+	algorithm WrapAlgorithm
+	method    keyexchange.Method
+	public    []byte
+}
+
+// ECDHRecipient returns the recipient of an X25519 or P-256 public value, as returned by keyexchange.PrivateKey.PublicKey.
+// Every envelope uses a new ephemeral key pair, the data key being derived from the agreed secret.
+// The public value is validated when sealing.
+func ECDHRecipient(method keyexchange.Method, public []byte) (Recipient, error) {
+	algorithm, err := ecdhAlgorithm(method)
+	if err != nil {
+		return nil, err
+	}
+	return ecdhRecipient{algorithm: algorithm, method: method, public: append([]byte(nil), public...)}, nil
+}
+
+func (r ecdhRecipient) Algorithm() WrapAlgorithm {
+	return r.algorithm
+}
+
+func (r ecdhRecipient) wrapKey(random io.Reader, header []byte) (dataKey, wrappedKey []byte, err error) {
+	ephemeral, err := keyexchange.GenerateKey(r.method, random)
+	if err != nil {
+		return nil, nil, err
+	}
+	wrappedKey = ephemeral.PublicKey()
+	// both public values and the header are the salt, binding the data key to this exchange
+	salt := append(append(append([]byte(nil), header...), wrappedKey...), r.public...)
+	dataKey, err = ephemeral.DeriveKey(r.public, salt, []byte(ecdhInfo), dataKeySize)
+	if err != nil {
+		return nil, nil, err
+	}
+	return dataKey, wrappedKey, nil
+}
+
+type ecdhIdentity struct {
+	algorithm WrapAlgorithm
+	priv      *keyexchange.PrivateKey
+}
+
+// ECDHIdentity returns the identity of an X25519 or P-256 private key
+func ECDHIdentity(priv *keyexchange.PrivateKey) (Identity, error) {
+	algorithm, err := ecdhAlgorithm(priv.Method())
+	if err != nil {
+		return nil, err
+	}
+	return ecdhIdentity{algorithm: algorithm, priv: priv}, nil
+}
+
+func (i ecdhIdentity) Algorithm() WrapAlgorithm {
+	return i.algorithm
+}
+
+func (i ecdhIdentity) unwrapKey(wrappedKey, header []byte) ([]byte, error) {
+	salt := append(append(append([]byte(nil), header...), wrappedKey...), i.priv.PublicKey()...)
+	dataKey, err := i.priv.DeriveKey(wrappedKey, salt, []byte(ecdhInfo), dataKeySize)
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	return dataKey, nil
+}
+
+func ecdhAlgorithm(method keyexchange.Method) (WrapAlgorithm, error) {
+	switch method {
+	case keyexchange.X25519:
+		return X25519, nil
+	case keyexchange.P256:
+		return P256, nil
+	}
+	return 0, ErrUnsupported
+}
@@ -0,0 +1,87 @@
+package keyexchange
+
+import (
+	"crypto/elliptic"
+	"golang.org/x/crypto/curve25519"
+	"io"
+	"math/big"
+)
+
+// X25519 is the elliptic curve Diffie-Hellman method over Curve25519 (RFC 7748). Keys and secrets are 32 bytes long.
+var X25519 Method = x25519{}
+
+// P256 is the elliptic curve Diffie-Hellman method over NIST P-256. Public values are uncompressed points
+// of 65 bytes and the secret is the 32-byte x coordinate of the shared point.
+var P256 Method = nistCurve{curve: elliptic.P256()}
+
+type x25519 struct{}
+
+func (x25519) Name() string {
+	return "X25519"
+}
+
+func (x25519) generate(random io.Reader) (private, public []byte, err error) {
+	private = make([]byte, curve25519.ScalarSize)
+	if _, err := io.ReadFull(random, private); err != nil {
+		return nil, nil, err
+	}
+	public, err = curve25519.X25519(private, curve25519.Basepoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	return private, public, nil
+}
+
+func (x25519) public(private []byte) ([]byte, error) {
+	if len(private) != curve25519.ScalarSize {
+		return nil, ErrInvalidPrivateKey
+	}
+	return curve25519.X25519(private, curve25519.Basepoint)
+}
+
+func (x25519) sharedSecret(private, peerPublic []byte) ([]byte, error) {
+	if len(peerPublic) != curve25519.PointSize {
+		return nil, ErrInvalidPublicKey
+	}
+	// X25519 fails on the low order points, that would give an all zero secret
+	secret, err := curve25519.X25519(private, peerPublic)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+	return secret, nil
+}
+
+type nistCurve struct {
+	curve elliptic.Curve
+}
+
+func (c nistCurve) Name() string {
+	return "ECDH " + c.curve.Params().Name
+}
+
+func (c nistCurve) generate(random io.Reader) (private, public []byte, err error) {
+	private, x, y, err := elliptic.GenerateKey(c.curve, random)
+	if err != nil {
+		return nil, nil, err
+	}
+	return private, elliptic.Marshal(c.curve, x, y), nil
+}
+
+func (c nistCurve) public(private []byte) ([]byte, error) {
+	d := new(big.Int).SetBytes(private)
+	if len(private) != (c.curve.Params().BitSize+7)/8 || d.Sign() == 0 || d.Cmp(c.curve.Params().N) >= 0 {
+		return nil, ErrInvalidPrivateKey
+	}
+	x, y := c.curve.ScalarBaseMult(private)
+	return elliptic.Marshal(c.curve, x, y), nil
+}
+
+func (c nistCurve) sharedSecret(private, peerPublic []byte) ([]byte, error) {
+	// Unmarshal rejects the points that are not on the curve, and the point at infinity cannot be encoded
+	x, y := elliptic.Unmarshal(c.curve, peerPublic)
+	if x == nil {
+		return nil, ErrInvalidPublicKey
+	}
+	sx, _ := c.curve.ScalarMult(x, y, private)
+	return sx.FillBytes(make([]byte, (c.curve.Params().BitSize+7)/8)), nil
+}
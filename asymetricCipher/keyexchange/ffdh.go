@@ -0,0 +1,54 @@
+package keyexchange
+
+import (
+	"io"
+	"math/big"
+)
+
+type ffdh struct {
+	group *Group
+}
+
+// FFDH returns the finite field Diffie-Hellman method over group. Public values and secrets are as long as the prime.
+func FFDH(group *Group) Method {
+	return ffdh{group: group}
+}
+
+func (m ffdh) Name() string {
+	return "FFDH " + m.group.Name
+}
+
+func (m ffdh) generate(random io.Reader) (private, public []byte, err error) {
+	size := m.group.Size()
+	// The private exponent is uniform in [1, Q-1], the 64 extra random bits make the modulo bias negligible
+	buf := make([]byte, size+8)
+	if _, err := io.ReadFull(random, buf); err != nil {
+		return nil, nil, err
+	}
+	qMinusOne := new(big.Int).Sub(m.group.Q, bigOne)
+	x := new(big.Int).SetBytes(buf)
+	x.Mod(x, qMinusOne).Add(x, bigOne)
+
+	y := new(big.Int).Exp(m.group.G, x, m.group.P)
+	return x.FillBytes(make([]byte, size)), y.FillBytes(make([]byte, size)), nil
+}
+
+func (m ffdh) public(private []byte) ([]byte, error) {
+	x := new(big.Int).SetBytes(private)
+	if len(private) != m.group.Size() || x.Sign() <= 0 || x.Cmp(m.group.Q) >= 0 {
+		return nil, ErrInvalidPrivateKey
+	}
+	return x.Exp(m.group.G, x, m.group.P).FillBytes(make([]byte, m.group.Size())), nil
+}
+
+func (m ffdh) sharedSecret(private, peerPublic []byte) ([]byte, error) {
+	if len(peerPublic) != m.group.Size() {
+		return nil, ErrInvalidPublicKey
+	}
+	y := new(big.Int).SetBytes(peerPublic)
+	if err := m.group.ValidatePublic(y); err != nil {
+		return nil, err
+	}
+	x := new(big.Int).SetBytes(private)
+	return y.Exp(y, x, m.group.P).FillBytes(make([]byte, m.group.Size())), nil
+}
@@ -0,0 +1,121 @@
+package keyexchange
+
+import (
+	"math/big"
+	"sync"
+)
+
+// A Group is a finite field Diffie-Hellman group: P is a safe prime, P = 2Q+1, and G generates the subgroup of order Q
+type Group struct {
+	Name string
+	P    *big.Int
+	Q    *big.Int
+	G    *big.Int
+}
+
+// modp14 is the 2048-bit MODP group, group 14 of RFC 3526
+const modp14 = "FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD1" +
+	"29024E088A67CC74020BBEA63B139B22514A08798E3404DD" +
+	"EF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245" +
+	"E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED" +
+	"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3D" +
+	"C2007CB8A163BF0598DA48361C55D39A69163FA8FD24CF5F" +
+	"83655D23DCA3AD961C62F356208552BB9ED529077096966D" +
+	"670C354E4ABC9804F1746C08CA18217C32905E462E36CE3B" +
+	"E39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9" +
+	"DE2BCBF6955817183995497CEA956AE515D2261898FA0510" +
+	"15728E5A8AACAA68FFFFFFFFFFFFFFFF"
+
+// modp15 is the 3072-bit MODP group, group 15 of RFC 3526
+const modp15 = "FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD1" +
+	"29024E088A67CC74020BBEA63B139B22514A08798E3404DD" +
+	"EF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245" +
+	"E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED" +
+	"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3D" +
+	"C2007CB8A163BF0598DA48361C55D39A69163FA8FD24CF5F" +
+	"83655D23DCA3AD961C62F356208552BB9ED529077096966D" +
+	"670C354E4ABC9804F1746C08CA18217C32905E462E36CE3B" +
+	"E39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9" +
+	"DE2BCBF6955817183995497CEA956AE515D2261898FA0510" +
+	"15728E5A8AAAC42DAD33170D04507A33A85521ABDF1CBA64" +
+	"ECFB850458DBEF0A8AEA71575D060C7DB3970F85A6E1E4C7" +
+	"ABF5AE8CDB0933D71E8C94E04A25619DCEE3D2261AD2EE6B" +
+	"F12FFA06D98A0864D87602733EC86A64521F2B18177B200C" +
+	"BBE117577A615D6C770988C0BAD946E208E24FA074E5AB31" +
+	"43DB5BFCE0FD108E4B82D120A93AD2CAFFFFFFFFFFFFFFFF"
+
+// modp16 is the 4096-bit MODP group, group 16 of RFC 3526
+const modp16 = "FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD1" +
+	"29024E088A67CC74020BBEA63B139B22514A08798E3404DD" +
+	"EF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245" +
+	"E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED" +
+	"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3D" +
+	"C2007CB8A163BF0598DA48361C55D39A69163FA8FD24CF5F" +
+	"83655D23DCA3AD961C62F356208552BB9ED529077096966D" +
+	"670C354E4ABC9804F1746C08CA18217C32905E462E36CE3B" +
+	"E39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9" +
+	"DE2BCBF6955817183995497CEA956AE515D2261898FA0510" +
+	"15728E5A8AAAC42DAD33170D04507A33A85521ABDF1CBA64" +
+	"ECFB850458DBEF0A8AEA71575D060C7DB3970F85A6E1E4C7" +
+	"ABF5AE8CDB0933D71E8C94E04A25619DCEE3D2261AD2EE6B" +
+	"F12FFA06D98A0864D87602733EC86A64521F2B18177B200C" +
+	"BBE117577A615D6C770988C0BAD946E208E24FA074E5AB31" +
+	"43DB5BFCE0FD108E4B82D120A92108011A723C12A787E6D7" +
+	"88719A10BDBA5B2699C327186AF4E23C1A946834B6150BDA" +
+	"2583E9CA2AD44CE8DBBBC2DB04DE8EF92E8EFC141FBECAA6" +
+	"287C59474E6BC05D99B2964FA090C3A2233BA186515BE7ED" +
+	"1F612970CEE2D7AFB81BDD762170481CD0069127D5B05AA9" +
+	"93B4EA988D8FDDC186FFB7DC90A6C08F4DF435C934063199" +
+	"FFFFFFFFFFFFFFFF"
+
+var (
+	group14, group15, group16 *Group
+	groupsOnce                sync.Once
+)
+
+func loadGroups() {
+	group14 = newGroup("modp2048", modp14)
+	group15 = newGroup("modp3072", modp15)
+	group16 = newGroup("modp4096", modp16)
+}
+
+func newGroup(name string, prime string) *Group {
+	p, _ := new(big.Int).SetString(prime, 16)
+	return &Group{Name: name, P: p, Q: new(big.Int).Rsh(p, 1), G: big.NewInt(2)}
+}
+
+// Group14 returns the 2048-bit group of RFC 3526
+func Group14() *Group {
+	groupsOnce.Do(loadGroups)
+	return group14
+}
+
+// Group15 returns the 3072-bit group of RFC 3526
+func Group15() *Group {
+	groupsOnce.Do(loadGroups)
+	return group15
+}
+
+// Group16 returns the 4096-bit group of RFC 3526
+func Group16() *Group {
+	groupsOnce.Do(loadGroups)
+	return group16
+}
+
+// ValidatePublic checks that y is a valid public value of the group: 1 < y < P-1 and y^Q = 1 mod P.
+// The last check rejects values outside the subgroup of order Q, which would leak bits of the private exponent.
+func (g *Group) ValidatePublic(y *big.Int) error {
+	pMinusOne := new(big.Int).Sub(g.P, bigOne)
+	if y.Cmp(bigOne) <= 0 || y.Cmp(pMinusOne) >= 0 {
+		return ErrInvalidPublicKey
+	}
+	if new(big.Int).Exp(y, g.Q, g.P).Cmp(bigOne) != 0 {
+		return ErrInvalidPublicKey
+	}
+	return nil
+}
+
+// Size returns the length in bytes of the public values and shared secrets of the group
+func (g *Group) Size() int {
+	return (g.P.BitLen() + 7) / 8
+}
@@ -0,0 +1,95 @@
+// Package keyexchange lets two parties agree on a shared secret over a public channel, with finite field
+// Diffie-Hellman over the RFC 3526 groups or elliptic curve Diffie-Hellman over X25519 and P-256.
+// The raw shared secret should not be used as a key directly, DeriveKey turns it into one.
+package keyexchange
+
+import (
+	"crypto/sha256"
+	"errors"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"math/big"
+)
+
+var (
+	ErrInvalidPublicKey  = errors.New("keyexchange: invalid public key")
+	ErrInvalidPrivateKey = errors.New("keyexchange: invalid private key")
+)
+
+var bigOne = big.NewInt(1)
+
+// A Method is a key agreement algorithm. The keys are handled as bytes so every method can be used the same way.
+type Method interface {
+	Name() string
+	// generate returns a new private key and its public value
+	generate(random io.Reader) (private, public []byte, err error)
+	// public validates a private key and returns its public value
+	public(private []byte) ([]byte, error)
+	// sharedSecret validates the peer's public value and combines it with the private key
+	sharedSecret(private, peerPublic []byte) ([]byte, error)
+}
+
+// A PrivateKey is one party's secret for a key agreement
+type PrivateKey struct {
+	method  Method
+	private []byte
+	public  []byte
+}
+
+// GenerateKey generates a new key pair for method
+func GenerateKey(method Method, random io.Reader) (*PrivateKey, error) {
+	private, public, err := method.generate(random)
+	if err != nil {
+		return nil, err
+	}
+	return &PrivateKey{method: method, private: private, public: public}, nil
+}
+
+// NewPrivateKey restores a private key of method from the bytes returned by Bytes
+func NewPrivateKey(method Method, private []byte) (*PrivateKey, error) {
+	public, err := method.public(private)
+	if err != nil {
+		return nil, err
+	}
+	return &PrivateKey{method: method, private: append([]byte(nil), private...), public: public}, nil
+}
+
+// Bytes returns the private key, to be stored and restored with NewPrivateKey
+func (k *PrivateKey) Bytes() []byte {
+	return append([]byte(nil), k.private...)
+}
+
+// Method returns the method of the key
+func (k *PrivateKey) Method() Method {
+	return k.method
+}
+
+// PublicKey returns the public value that is sent to the peer
+func (k *PrivateKey) PublicKey() []byte {
+	return append([]byte(nil), k.public...)
+}
+
+// SharedSecret computes the secret shared with the owner of peerPublic.
+// The peer's value is validated first and ErrInvalidPublicKey is returned if it is not a valid point or group element.
+func (k *PrivateKey) SharedSecret(peerPublic []byte) ([]byte, error) {
+	return k.method.sharedSecret(k.private, peerPublic)
+}
+
+// DeriveKey agrees on a secret with the owner of peerPublic and derives a key of length bytes from it with DeriveKey
+func (k *PrivateKey) DeriveKey(peerPublic, salt, info []byte, length int) ([]byte, error) {
+	secret, err := k.SharedSecret(peerPublic)
+	if err != nil {
+		return nil, err
+	}
+	return DeriveKey(secret, salt, info, length)
+}
+
+// DeriveKey derives a key of length bytes from a shared secret with HKDF-SHA256 (RFC 5869).
+// Info binds the key to its use, so that different keys derived from the same secret are independent.
+func DeriveKey(secret, salt, info []byte, length int) ([]byte, error) {
+	key := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -0,0 +1,167 @@
+package keyexchange_test
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/keyexchange"
+	"math/big"
+	"testing"
+)
+
+func TestAgreement(t *testing.T) {
+	methods := []keyexchange.Method{
+		keyexchange.X25519,
+		keyexchange.P256,
+		keyexchange.FFDH(keyexchange.Group14()),
+	}
+
+	for _, method := range methods {
+		t.Run(method.Name(), func(t *testing.T) {
+			alice, err := keyexchange.GenerateKey(method, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bob, err := keyexchange.GenerateKey(method, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			aliceSecret, err := alice.SharedSecret(bob.PublicKey())
+			if err != nil {
+				t.Fatal(err)
+			}
+			bobSecret, err := bob.SharedSecret(alice.PublicKey())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(aliceSecret, bobSecret) {
+				t.Fatal("the two parties computed different secrets")
+			}
+
+			aliceKey, err := alice.DeriveKey(bob.PublicKey(), nil, []byte("test"), 32)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bobKey, err := bob.DeriveKey(alice.PublicKey(), nil, []byte("test"), 32)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(aliceKey, bobKey) || bytes.Equal(aliceKey, aliceSecret[:32]) {
+				t.Fatal("the derived keys differ or are the raw secret")
+			}
+
+			// a key restored from its bytes agrees on the same secret
+			restored, err := keyexchange.NewPrivateKey(method, alice.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(restored.PublicKey(), alice.PublicKey()) {
+				t.Fatal("the restored key has another public value")
+			}
+		})
+	}
+}
+
+func TestFFDHRejectsPublicValues(t *testing.T) {
+	group := keyexchange.Group14()
+	key, err := keyexchange.GenerateKey(keyexchange.FFDH(group), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pMinusOne := new(big.Int).Sub(group.P, big.NewInt(1))
+	// G^Q = 1, so -G is not in the subgroup of order Q
+	outside := new(big.Int).Sub(group.P, group.G)
+	values := map[string]*big.Int{
+		"0":                    big.NewInt(0),
+		"1":                    big.NewInt(1),
+		"p-1":                  pMinusOne,
+		"p":                    group.P,
+		"outside the subgroup": outside,
+	}
+	for name, y := range values {
+		if _, err := key.SharedSecret(y.FillBytes(make([]byte, group.Size()))); err != keyexchange.ErrInvalidPublicKey {
+			t.Errorf("the public value %s was accepted: %v", name, err)
+		}
+	}
+	if _, err := key.SharedSecret(key.PublicKey()[1:]); err != keyexchange.ErrInvalidPublicKey {
+		t.Errorf("a short public value was accepted: %v", err)
+	}
+}
+
+func TestP256RejectsOffCurvePoints(t *testing.T) {
+	key, err := keyexchange.GenerateKey(keyexchange.P256, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer, err := keyexchange.GenerateKey(keyexchange.P256, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offCurve := peer.PublicKey()
+	offCurve[len(offCurve)-1] ^= 1
+	if elliptic.P256().IsOnCurve(new(big.Int).SetBytes(offCurve[1:33]), new(big.Int).SetBytes(offCurve[33:])) {
+		t.Fatal("the modified point is still on the curve")
+	}
+	if _, err := key.SharedSecret(offCurve); err != keyexchange.ErrInvalidPublicKey {
+		t.Errorf("a point off the curve was accepted: %v", err)
+	}
+
+	// only uncompressed points are accepted
+	x, y := elliptic.Unmarshal(elliptic.P256(), peer.PublicKey())
+	compressed := elliptic.MarshalCompressed(elliptic.P256(), x, y)
+	for _, public := range [][]byte{nil, make([]byte, 65), compressed} {
+		if _, err := key.SharedSecret(public); err != keyexchange.ErrInvalidPublicKey {
+			t.Errorf("the public value %x was accepted: %v", public, err)
+		}
+	}
+}
+
+func TestX25519RejectsLowOrderPoints(t *testing.T) {
+	key, err := keyexchange.GenerateKey(keyexchange.X25519, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := key.SharedSecret(make([]byte, 32)); err != keyexchange.ErrInvalidPublicKey {
+		t.Errorf("the zero point was accepted: %v", err)
+	}
+	if _, err := key.SharedSecret(make([]byte, 31)); err != keyexchange.ErrInvalidPublicKey {
+		t.Errorf("a short point was accepted: %v", err)
+	}
+}
+
+// TestGroups checks the hand-copied RFC 3526 primes: P and Q = (P-1)/2 are prime, G generates the subgroup of order Q
+func TestGroups(t *testing.T) {
+	groups := []struct {
+		group *keyexchange.Group
+		bits  int
+	}{
+		{keyexchange.Group14(), 2048},
+		{keyexchange.Group15(), 3072},
+		{keyexchange.Group16(), 4096},
+	}
+
+	for _, test := range groups {
+		group := test.group
+		t.Run(group.Name, func(t *testing.T) {
+			if group.P.BitLen() != test.bits {
+				t.Fatalf("P has %d bits, want %d", group.P.BitLen(), test.bits)
+			}
+			q := new(big.Int).Rsh(group.P, 1)
+			if q.Cmp(group.Q) != 0 {
+				t.Fatal("Q is not (P-1)/2")
+			}
+			if !group.P.ProbablyPrime(1) {
+				t.Fatal("P is not prime")
+			}
+			if !group.Q.ProbablyPrime(1) {
+				t.Fatal("Q is not prime")
+			}
+			if new(big.Int).Exp(group.G, group.Q, group.P).Cmp(big.NewInt(1)) != 0 {
+				t.Fatal("G does not generate the subgroup of order Q")
+			}
+		})
+	}
+}
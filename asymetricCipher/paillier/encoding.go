@@ -0,0 +1,134 @@
+package paillier
+
+import (
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"math/big"
+)
+
+// PEM block types read and written by this package. Paillier keys have no standard encoding, the DER structures
+// below follow PKCS #1: the private key only keeps the primes, everything else is computed again when it is parsed.
+const (
+	pemPrivateKey = "PAILLIER PRIVATE KEY"
+	pemPublicKey  = "PAILLIER PUBLIC KEY"
+)
+
+var (
+	ErrNoPEMBlock     = errors.New("paillier: no PEM block found")
+	ErrUnsupportedPEM = errors.New("paillier: unsupported PEM block type")
+)
+
+type privateKeyDER struct {
+	Version int
+	N       *big.Int
+	P       *big.Int
+	Q       *big.Int
+}
+
+type publicKeyDER struct {
+	N *big.Int
+}
+
+// MarshalPrivateKey returns the ASN.1 DER form of the private key
+func MarshalPrivateKey(key *PrivateKey) ([]byte, error) {
+	return asn1.Marshal(privateKeyDER{N: key.N, P: key.p, Q: key.q})
+}
+
+// ParsePrivateKey parses a private key in ASN.1 DER form, checking that its primes give its modulus
+func ParsePrivateKey(der []byte) (*PrivateKey, error) {
+	var raw privateKeyDER
+	rest, err := asn1.Unmarshal(der, &raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("paillier: trailing data after the private key")
+	}
+	if raw.Version != 0 {
+		return nil, errors.New("paillier: unsupported private key version")
+	}
+	if raw.P == nil || raw.Q == nil || raw.P.Cmp(bigOne) <= 0 || raw.Q.Cmp(bigOne) <= 0 {
+		return nil, errors.New("paillier: invalid private key")
+	}
+
+	key, err := NewPrivateKey(raw.P, raw.Q)
+	if err != nil {
+		return nil, err
+	}
+	if raw.N == nil || key.N.Cmp(raw.N) != 0 {
+		return nil, errors.New("paillier: the modulus of the private key is not p*q")
+	}
+	return key, nil
+}
+
+// MarshalPublicKey returns the ASN.1 DER form of the public key
+func MarshalPublicKey(pub *PublicKey) ([]byte, error) {
+	return asn1.Marshal(publicKeyDER{N: pub.N})
+}
+
+// ParsePublicKey parses a public key in ASN.1 DER form
+func ParsePublicKey(der []byte) (*PublicKey, error) {
+	var raw publicKeyDER
+	rest, err := asn1.Unmarshal(der, &raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("paillier: trailing data after the public key")
+	}
+	if raw.N == nil || raw.N.Sign() <= 0 {
+		return nil, errors.New("paillier: invalid public key")
+	}
+	return &PublicKey{N: raw.N}, nil
+}
+
+// EncodePrivateKeyPEM returns the private key as a "PAILLIER PRIVATE KEY" PEM block
+func EncodePrivateKeyPEM(key *PrivateKey) ([]byte, error) {
+	der, err := MarshalPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemPrivateKey, Bytes: der}), nil
+}
+
+// DecodePrivateKeyPEM reads the first PEM block of data, a "PAILLIER PRIVATE KEY"
+func DecodePrivateKeyPEM(data []byte) (*PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrNoPEMBlock
+	}
+	if block.Type != pemPrivateKey {
+		return nil, ErrUnsupportedPEM
+	}
+	return ParsePrivateKey(block.Bytes)
+}
+
+// EncodePublicKeyPEM returns the public key as a "PAILLIER PUBLIC KEY" PEM block
+func EncodePublicKeyPEM(pub *PublicKey) ([]byte, error) {
+	der, err := MarshalPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemPublicKey, Bytes: der}), nil
+}
+
+// DecodePublicKeyPEM reads the first PEM block of data, a "PAILLIER PUBLIC KEY" or the public part of a "PAILLIER PRIVATE KEY"
+func DecodePublicKeyPEM(data []byte) (*PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrNoPEMBlock
+	}
+
+	switch block.Type {
+	case pemPublicKey:
+		return ParsePublicKey(block.Bytes)
+	case pemPrivateKey:
+		key, err := ParsePrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return &key.PublicKey, nil
+	}
+	return nil, ErrUnsupportedPEM
+}
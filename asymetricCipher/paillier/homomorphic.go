@@ -0,0 +1,43 @@
+package paillier
+
+import (
+	"math/big"
+)
+
+// Add returns a ciphertext of m1 + m2 mod N from the ciphertexts c1 of m1 and c2 of m2, as the product c1*c2 mod N^2
+func Add(pub *PublicKey, c1, c2 *big.Int) (*big.Int, error) {
+	if err := pub.checkCiphertext(c1); err != nil {
+		return nil, err
+	}
+	if err := pub.checkCiphertext(c2); err != nil {
+		return nil, err
+	}
+	c := new(big.Int).Mul(c1, c2)
+	return c.Mod(c, pub.nSquared()), nil
+}
+
+// AddPlaintext returns a ciphertext of m + k mod N from the ciphertext c of m, multiplying it by (N+1)^k = 1 + k*N.
+// The result is not re-randomized, the ciphertexts are linked.
+func AddPlaintext(pub *PublicKey, c, k *big.Int) (*big.Int, error) {
+	if err := pub.checkCiphertext(c); err != nil {
+		return nil, err
+	}
+	gk := new(big.Int).Mod(k, pub.N)
+	gk.Mul(gk, pub.N).Add(gk, bigOne)
+	return gk.Mul(gk, c).Mod(gk, pub.nSquared()), nil
+}
+
+// MulScalar returns a ciphertext of k*m mod N from the ciphertext c of m, as c^k mod N^2.
+// A negative k multiplies by k mod N, which is -k for the messages read as signed numbers.
+func MulScalar(pub *PublicKey, c, k *big.Int) (*big.Int, error) {
+	if err := pub.checkCiphertext(c); err != nil {
+		return nil, err
+	}
+	e := new(big.Int).Mod(k, pub.N)
+	return e.Exp(c, e, pub.nSquared()), nil
+}
+
+// EncryptedZero returns the trivial ciphertext 1 of 0, the neutral element of Add
+func EncryptedZero() *big.Int {
+	return big.NewInt(1)
+}
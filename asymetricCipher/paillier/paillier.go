@@ -0,0 +1,161 @@
+// Package paillier implements the Paillier cryptosystem, whose ciphertexts can be added together without being decrypted.
+package paillier
+
+import (
+	"errors"
+	"fmt"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/rsa"
+	"io"
+	"math/big"
+)
+
+const (
+	// DefaultKeySize is the modulus size in bits of the generated keys
+	DefaultKeySize = 2048
+	MinKeySize     = 1024
+	MaxKeySize     = 4096
+)
+
+var (
+	ErrMessageTooLarge = errors.New("paillier: message must be in [0, N)")
+	ErrInvalidCipher   = errors.New("paillier: invalid ciphertext")
+)
+
+var bigOne = big.NewInt(1)
+
+// A PublicKey represents the public part of a Paillier key. The generator is N+1.
+type PublicKey struct {
+	N *big.Int // modulus
+}
+
+// Size returns the size in bytes of the ciphertexts, which are numbers modulo N^2
+func (pk *PublicKey) Size() int {
+	return (pk.nSquared().BitLen() + 7) / 8
+}
+
+func (pk *PublicKey) nSquared() *big.Int {
+	return new(big.Int).Mul(pk.N, pk.N)
+}
+
+// A PrivateKey represents a Paillier key
+type PrivateKey struct {
+	PublicKey          // public part.
+	lambda    *big.Int // lcm(p-1, q-1)
+	mu        *big.Int // lambda^-1 mod N
+	p, q      *big.Int // prime factors of N
+}
+
+// GenerateKey generates a Paillier key pair with a modulus of bits bits, the primes being generated as for RSA
+func GenerateKey(random io.Reader, bits int) (*PrivateKey, error) {
+	if bits < MinKeySize || bits > MaxKeySize {
+		return nil, fmt.Errorf("paillier: key size must be from %d to %d bits, got %d", MinKeySize, MaxKeySize, bits)
+	}
+
+	for {
+		p, err := rsa.GeneratePrime(random, bits-bits/2)
+		if err != nil {
+			return nil, err
+		}
+		q, err := rsa.GeneratePrime(random, bits/2)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := NewPrivateKey(p, q)
+		if err != nil {
+			continue
+		}
+		if key.N.BitLen() != bits {
+			continue
+		}
+		return key, nil
+	}
+}
+
+// NewPrivateKey builds the Paillier key of the primes p and q.
+// The primality of p and q is not checked.
+func NewPrivateKey(p, q *big.Int) (*PrivateKey, error) {
+	if p.Cmp(q) == 0 {
+		return nil, errors.New("paillier: p and q must be different")
+	}
+
+	n := new(big.Int).Mul(p, q)
+	pMinusOne := new(big.Int).Sub(p, bigOne)
+	qMinusOne := new(big.Int).Sub(q, bigOne)
+
+	// gcd(N, phi) = 1 is needed for N+1 to be a valid generator, it holds for primes of the same size
+	phi := new(big.Int).Mul(pMinusOne, qMinusOne)
+	if new(big.Int).GCD(nil, nil, n, phi).Cmp(bigOne) != 0 {
+		return nil, errors.New("paillier: N and phi(N) must be co-prime")
+	}
+
+	gcd := new(big.Int).GCD(nil, nil, pMinusOne, qMinusOne)
+	lambda := new(big.Int).Quo(phi, gcd)
+
+	// with the generator N+1, L(g^lambda mod N^2) = lambda mod N
+	mu := new(big.Int).ModInverse(lambda, n)
+	if mu == nil {
+		return nil, errors.New("paillier: lambda is not invertible modulo N")
+	}
+
+	return &PrivateKey{
+		PublicKey: PublicKey{N: n},
+		lambda:    lambda,
+		mu:        mu,
+		p:         new(big.Int).Set(p),
+		q:         new(big.Int).Set(q),
+	}, nil
+}
+
+// Encrypt computes c = (N+1)^m * r^N mod N^2 for a fresh random r, so that equal messages give different ciphertexts.
+// (N+1)^m is 1 + m*N mod N^2, which saves one exponentiation.
+func Encrypt(random io.Reader, pub *PublicKey, m *big.Int) (*big.Int, error) {
+	if m.Sign() < 0 || m.Cmp(pub.N) >= 0 {
+		return nil, ErrMessageTooLarge
+	}
+	r, err := randomUnit(random, pub.N)
+	if err != nil {
+		return nil, err
+	}
+
+	c := new(big.Int).Exp(r, pub.N, pub.nSquared())
+	gm := new(big.Int).Mul(m, pub.N)
+	gm.Add(gm, bigOne)
+	return c.Mul(c, gm).Mod(c, pub.nSquared()), nil
+}
+
+// Decrypt recovers m = L(c^lambda mod N^2) * mu mod N, with L(x) = (x-1)/N
+func Decrypt(priv *PrivateKey, c *big.Int) (*big.Int, error) {
+	if err := priv.checkCiphertext(c); err != nil {
+		return nil, err
+	}
+	m := new(big.Int).Exp(c, priv.lambda, priv.nSquared())
+	m.Sub(m, bigOne).Quo(m, priv.N)
+	return m.Mul(m, priv.mu).Mod(m, priv.N), nil
+}
+
+// checkCiphertext checks that c is a unit modulo N^2
+func (pk *PublicKey) checkCiphertext(c *big.Int) error {
+	if c.Sign() <= 0 || c.Cmp(pk.nSquared()) >= 0 {
+		return ErrInvalidCipher
+	}
+	if new(big.Int).GCD(nil, nil, c, pk.N).Cmp(bigOne) != 0 {
+		return ErrInvalidCipher
+	}
+	return nil
+}
+
+// randomUnit returns a random number in [1, n) co-prime to n
+func randomUnit(random io.Reader, n *big.Int) (*big.Int, error) {
+	buf := make([]byte, (n.BitLen()+7)/8)
+	r := new(big.Int)
+	for {
+		if _, err := io.ReadFull(random, buf); err != nil {
+			return nil, err
+		}
+		r.SetBytes(buf)
+		if r.Sign() > 0 && r.Cmp(n) < 0 && new(big.Int).GCD(nil, nil, r, n).Cmp(bigOne) == 0 {
+			return r, nil
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package paillier_test
+
+import (
+	"crypto/rand"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/paillier"
+	"math/big"
+	"sync"
+	"testing"
+)
+
+var (
+	keyOnce sync.Once
+	testKey *paillier.PrivateKey
+)
+
+// key returns a key of the minimum size, generated once for all the tests
+func key(t *testing.T) *paillier.PrivateKey {
+	t.Helper()
+	keyOnce.Do(func() {
+		var err error
+		if testKey, err = paillier.GenerateKey(rand.Reader, paillier.MinKeySize); err != nil {
+			t.Fatal(err)
+		}
+	})
+	return testKey
+}
+
+func encrypt(t *testing.T, priv *paillier.PrivateKey, m *big.Int) *big.Int {
+	t.Helper()
+	c, err := paillier.Encrypt(rand.Reader, &priv.PublicKey, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func checkDecrypt(t *testing.T, priv *paillier.PrivateKey, c, want *big.Int) {
+	t.Helper()
+	got, err := paillier.Decrypt(priv, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Cmp(want) != 0 {
+		t.Fatalf("Decrypt = %v, want %v", got, want)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	priv := key(t)
+	nMinusOne := new(big.Int).Sub(priv.N, big.NewInt(1))
+	for _, m := range []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(123456789), nMinusOne} {
+		c := encrypt(t, priv, m)
+		if again := encrypt(t, priv, m); again.Cmp(c) == 0 {
+			t.Fatalf("two encryptions of %v are equal", m)
+		}
+		checkDecrypt(t, priv, c, m)
+	}
+
+	for _, m := range []*big.Int{big.NewInt(-1), priv.N} {
+		if _, err := paillier.Encrypt(rand.Reader, &priv.PublicKey, m); err != paillier.ErrMessageTooLarge {
+			t.Fatalf("Encrypt(%v) = %v, want ErrMessageTooLarge", m, err)
+		}
+	}
+	nSquared := new(big.Int).Mul(priv.N, priv.N)
+	for _, c := range []*big.Int{big.NewInt(0), nSquared, priv.N} {
+		if _, err := paillier.Decrypt(priv, c); err != paillier.ErrInvalidCipher {
+			t.Fatalf("Decrypt(%v) = %v, want ErrInvalidCipher", c, err)
+		}
+	}
+}
+
+func TestHomomorphic(t *testing.T) {
+	priv := key(t)
+	pub := &priv.PublicKey
+	a, b := big.NewInt(1234), big.NewInt(5678)
+	ca, cb := encrypt(t, priv, a), encrypt(t, priv, b)
+	nMinusOne := new(big.Int).Sub(priv.N, big.NewInt(1))
+
+	sum, err := paillier.Add(pub, ca, cb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDecrypt(t, priv, sum, big.NewInt(1234+5678))
+
+	// the sums are taken modulo N
+	wrapped, err := paillier.Add(pub, encrypt(t, priv, nMinusOne), encrypt(t, priv, big.NewInt(2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDecrypt(t, priv, wrapped, big.NewInt(1))
+
+	zero, err := paillier.Add(pub, paillier.EncryptedZero(), ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDecrypt(t, priv, zero, a)
+
+	plus, err := paillier.AddPlaintext(pub, ca, big.NewInt(66))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDecrypt(t, priv, plus, big.NewInt(1234+66))
+
+	minus, err := paillier.AddPlaintext(pub, ca, big.NewInt(-1234))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDecrypt(t, priv, minus, big.NewInt(0))
+
+	product, err := paillier.MulScalar(pub, ca, big.NewInt(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDecrypt(t, priv, product, big.NewInt(1234000))
+
+	// a negative scalar gives -k*m mod N
+	negative, err := paillier.MulScalar(pub, ca, big.NewInt(-1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDecrypt(t, priv, negative, new(big.Int).Sub(priv.N, a))
+
+	for name, call := range map[string]func() (*big.Int, error){
+		"Add":          func() (*big.Int, error) { return paillier.Add(pub, big.NewInt(0), cb) },
+		"AddPlaintext": func() (*big.Int, error) { return paillier.AddPlaintext(pub, priv.N, big.NewInt(1)) },
+		"MulScalar":    func() (*big.Int, error) { return paillier.MulScalar(pub, big.NewInt(0), big.NewInt(2)) },
+	} {
+		if _, err := call(); err != paillier.ErrInvalidCipher {
+			t.Errorf("%s of an invalid ciphertext = %v, want ErrInvalidCipher", name, err)
+		}
+	}
+}
+
+func TestTally(t *testing.T) {
+	priv := key(t)
+	tally := paillier.NewTally(&priv.PublicKey)
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 10; i++ {
+		c := encrypt(t, priv, big.NewInt(int64(i)))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := tally.Add(c); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := tally.Add(big.NewInt(0)); err != paillier.ErrInvalidCipher {
+		t.Fatalf("adding an invalid ciphertext = %v, want ErrInvalidCipher", err)
+	}
+
+	sum, count, err := tally.Result(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 10 || sum.Int64() != 55 {
+		t.Fatalf("Result = %v of %d values, want 55 of 10", sum, count)
+	}
+}
+
+func TestPEM(t *testing.T) {
+	priv := key(t)
+
+	encoded, err := paillier.EncodePrivateKeyPEM(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := paillier.DecodePrivateKeyPEM(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := encrypt(t, priv, big.NewInt(42))
+	checkDecrypt(t, decoded, c, big.NewInt(42))
+
+	// the public part of a private key file
+	pub, err := paillier.DecodePublicKeyPEM(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub.N.Cmp(priv.N) != 0 {
+		t.Fatal("the public key of the private PEM has another modulus")
+	}
+
+	encodedPublic, err := paillier.EncodePublicKeyPEM(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub, err = paillier.DecodePublicKeyPEM(encodedPublic); err != nil {
+		t.Fatal(err)
+	}
+	if pub.N.Cmp(priv.N) != 0 {
+		t.Fatal("the public PEM has another modulus")
+	}
+	if _, err := paillier.DecodePrivateKeyPEM(encodedPublic); err != paillier.ErrUnsupportedPEM {
+		t.Fatalf("DecodePrivateKeyPEM of a public key = %v, want ErrUnsupportedPEM", err)
+	}
+	if _, err := paillier.DecodePublicKeyPEM([]byte("not a PEM file")); err != paillier.ErrNoPEMBlock {
+		t.Fatalf("DecodePublicKeyPEM of garbage = %v, want ErrNoPEMBlock", err)
+	}
+
+	// the primes must give the stored modulus
+	der, err := paillier.MarshalPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der[len(der)/3] ^= 1
+	if _, err := paillier.ParsePrivateKey(der); err == nil {
+		t.Fatal("a damaged private key was parsed")
+	}
+}
@@ -0,0 +1,49 @@
+package paillier
+
+import (
+	"math/big"
+	"sync"
+)
+
+// A Tally sums encrypted values as they arrive without ever decrypting them.
+// Only the holder of the private key can read the total, and none of the values on their own.
+// It is safe for concurrent use.
+type Tally struct {
+	mu    sync.Mutex
+	pub   *PublicKey
+	sum   *big.Int
+	count int
+}
+
+// NewTally returns an empty tally for values encrypted under pub
+func NewTally(pub *PublicKey) *Tally {
+	return &Tally{pub: pub, sum: EncryptedZero()}
+}
+
+// Add adds the encrypted value c to the tally and returns the number of values counted so far
+func (t *Tally) Add(c *big.Int) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sum, err := Add(t.pub, t.sum, c)
+	if err != nil {
+		return t.count, err
+	}
+	t.sum = sum
+	t.count++
+	return t.count, nil
+}
+
+// Sum returns the encrypted sum of the values and their number
+func (t *Tally) Sum() (*big.Int, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return new(big.Int).Set(t.sum), t.count
+}
+
+// Result decrypts the sum of the values with the private key of the tally
+func (t *Tally) Result(priv *PrivateKey) (*big.Int, int, error) {
+	sum, count := t.Sum()
+	m, err := Decrypt(priv, sum)
+	return m, count, err
+}
@@ -0,0 +1,57 @@
+// Package attacks implements the classic attacks on textbook RSA, to show why small exponents,
+// shared moduli and badly chosen primes break it. Every attack returns the recovered message or key.
+package attacks
+
+import (
+	"errors"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/rsa"
+	"math/big"
+)
+
+var (
+	ErrNotVulnerable = errors.New("attacks: the key is not vulnerable to this attack")
+	ErrNotFound      = errors.New("attacks: no factor found within the iteration limit")
+)
+
+var bigOne = big.NewInt(1)
+
+// RecoverKey rebuilds the private key of pub from one prime factor p of its modulus
+func RecoverKey(pub *rsa.PublicKey, p *big.Int) (*rsa.PrivateKey, error) {
+	q, rem := new(big.Int).QuoRem(pub.N, p, new(big.Int))
+	if rem.Sign() != 0 || p.Cmp(bigOne) <= 0 || q.Cmp(bigOne) <= 0 {
+		return nil, errors.New("attacks: p is not a factor of N")
+	}
+	return rsa.NewPrivateKey(p, q, pub.E)
+}
+
+// isSquare returns the square root of n when n is a perfect square
+func isSquare(n *big.Int) (*big.Int, bool) {
+	if n.Sign() < 0 {
+		return nil, false
+	}
+	root := new(big.Int).Sqrt(n)
+	return root, new(big.Int).Mul(root, root).Cmp(n) == 0
+}
+
+// nthRoot returns the integer k-th root of n, the largest x with x^k <= n, using Newton's method
+func nthRoot(n *big.Int, k int) *big.Int {
+	if n.Sign() == 0 {
+		return new(big.Int)
+	}
+	bigK := big.NewInt(int64(k))
+	kMinusOne := big.NewInt(int64(k - 1))
+
+	// Start above the root, Newton's iterations then decrease to it
+	x := new(big.Int).Lsh(bigOne, uint(n.BitLen()/k+1))
+	for {
+		// y = ((k-1)*x + n/x^(k-1)) / k
+		y := new(big.Int).Exp(x, kMinusOne, nil)
+		y.Quo(n, y)
+		y.Add(y, new(big.Int).Mul(kMinusOne, x))
+		y.Quo(y, bigK)
+		if y.Cmp(x) >= 0 {
+			return x
+		}
+		x = y
+	}
+}
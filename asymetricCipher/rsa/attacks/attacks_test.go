@@ -0,0 +1,135 @@
+package attacks_test
+
+import (
+	"crypto/rand"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/rsa"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/rsa/attacks"
+	"math/big"
+	"testing"
+)
+
+var message = new(big.Int).SetBytes([]byte("attack at dawn"))
+
+// textbook computes m^e mod N, the unpadded encryption that the broadcast and common modulus attacks break
+func textbook(pub *rsa.PublicKey, m *big.Int) *big.Int {
+	return new(big.Int).Exp(m, big.NewInt(int64(pub.E)), pub.N)
+}
+
+func TestWiener(t *testing.T) {
+	n, e, d, err := attacks.WienerKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recovered, p, q, err := attacks.Wiener(n, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recovered.Cmp(d) != 0 {
+		t.Fatalf("recovered d = %v, want %v", recovered, d)
+	}
+	if new(big.Int).Mul(p, q).Cmp(n) != 0 {
+		t.Fatalf("p*q is not N")
+	}
+
+	// a key with a small public exponent has a large d, out of reach of the attack
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := attacks.Wiener(key.N, big.NewInt(int64(key.E))); err != attacks.ErrNotVulnerable {
+		t.Fatalf("Wiener on a safe key = %v, want ErrNotVulnerable", err)
+	}
+}
+
+func TestHastad(t *testing.T) {
+	keys, err := attacks.SmallExponentKeys(rand.Reader, 1024, 3, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubs := make([]*rsa.PublicKey, len(keys))
+	ciphertexts := make([]*big.Int, len(keys))
+	for i, key := range keys {
+		pubs[i] = &key.PublicKey
+		ciphertexts[i] = textbook(pubs[i], message)
+	}
+
+	recovered, err := attacks.Hastad(pubs, ciphertexts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recovered.Cmp(message) != 0 {
+		t.Fatalf("recovered %q", recovered.Bytes())
+	}
+
+	if _, err := attacks.Hastad(pubs[:2], ciphertexts[:2]); err != attacks.ErrNotVulnerable {
+		t.Fatalf("Hastad with 2 ciphertexts for e = 3 = %v, want ErrNotVulnerable", err)
+	}
+}
+
+func TestCommonModulus(t *testing.T) {
+	key1, key2, err := attacks.CommonModulusKeys(rand.Reader, 1024, 3, rsa.DefaultExponent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c1 := textbook(&key1.PublicKey, message)
+	c2 := textbook(&key2.PublicKey, message)
+
+	recovered, err := attacks.CommonModulus(&key1.PublicKey, &key2.PublicKey, c1, c2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recovered.Cmp(message) != 0 {
+		t.Fatalf("recovered %q", recovered.Bytes())
+	}
+}
+
+func TestFermat(t *testing.T) {
+	key, err := attacks.CloseFactorsKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, q, err := attacks.Fermat(key.N, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if new(big.Int).Mul(p, q).Cmp(key.N) != 0 {
+		t.Fatalf("p*q is not N")
+	}
+	checkRecovered(t, &key.PublicKey, p)
+}
+
+func TestPollardRho(t *testing.T) {
+	key, err := attacks.SmallFactorKey(rand.Reader, 1024, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := attacks.PollardRho(key.N, 1<<24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRecovered(t, &key.PublicKey, p)
+}
+
+// checkRecovered rebuilds the private key from the factor p and checks that it decrypts a message encrypted to pub
+func checkRecovered(t *testing.T, pub *rsa.PublicKey, p *big.Int) {
+	t.Helper()
+	key, err := attacks.RecoverKey(pub, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := []byte("attack at dawn")
+	ciphertext, err := rsa.EncryptPKCS1v15(rand.Reader, pub, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := rsa.DecryptPKCS1v15(key, ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decrypted) != string(plaintext) {
+		t.Fatalf("decrypted %q", decrypted)
+	}
+}
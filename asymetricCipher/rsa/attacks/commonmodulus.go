@@ -0,0 +1,45 @@
+package attacks
+
+import (
+	"errors"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/rsa"
+	"math/big"
+)
+
+// CommonModulus recovers a message encrypted under two keys that share the modulus N but have co-prime exponents.
+// Extended Euclid gives a and b with a*e1 + b*e2 = 1, so c1^a * c2^b = m^(a*e1 + b*e2) = m mod N,
+// a negative power being taken on the inverse of the ciphertext.
+func CommonModulus(pub1, pub2 *rsa.PublicKey, c1, c2 *big.Int) (*big.Int, error) {
+	if pub1.N.Cmp(pub2.N) != 0 {
+		return nil, errors.New("attacks: the keys must share the modulus")
+	}
+	n := pub1.N
+	a, b := new(big.Int), new(big.Int)
+	gcd := new(big.Int).GCD(a, b, big.NewInt(int64(pub1.E)), big.NewInt(int64(pub2.E)))
+	if gcd.Cmp(bigOne) != 0 {
+		return nil, ErrNotVulnerable
+	}
+
+	m1, err := signedExp(c1, a, n)
+	if err != nil {
+		return nil, err
+	}
+	m2, err := signedExp(c2, b, n)
+	if err != nil {
+		return nil, err
+	}
+	return m1.Mul(m1, m2).Mod(m1, n), nil
+}
+
+// signedExp returns c^x mod n, where x can be negative
+func signedExp(c, x, n *big.Int) (*big.Int, error) {
+	if x.Sign() >= 0 {
+		return new(big.Int).Exp(c, x, n), nil
+	}
+	inv := new(big.Int).ModInverse(c, n)
+	if inv == nil {
+		// c shares a factor with n, gcd(c, n) factors it
+		return nil, errors.New("attacks: ciphertext is not invertible modulo N")
+	}
+	return inv.Exp(inv, new(big.Int).Neg(x), n), nil
+}
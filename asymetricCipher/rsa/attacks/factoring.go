@@ -0,0 +1,94 @@
+package attacks
+
+import (
+	"math/big"
+)
+
+// Fermat factors n = p*q when p and q are close, writing n = a^2 - b^2 = (a+b)(a-b) and trying a = ceil(sqrt(n)), a+1, ...
+// until a^2 - n is a square. When |p-q| is below n^(1/4) the first try already succeeds.
+func Fermat(n *big.Int, maxIterations int) (p, q *big.Int, err error) {
+	if n.Bit(0) == 0 {
+		return big.NewInt(2), new(big.Int).Rsh(n, 1), nil
+	}
+
+	a := new(big.Int).Sqrt(n)
+	if new(big.Int).Mul(a, a).Cmp(n) != 0 {
+		a.Add(a, bigOne)
+	}
+	// b2 = a^2 - n, updated as (a+1)^2 - n = b2 + 2a + 1
+	b2 := new(big.Int).Mul(a, a)
+	b2.Sub(b2, n)
+
+	for i := 0; i < maxIterations; i++ {
+		if b, ok := isSquare(b2); ok {
+			p = new(big.Int).Add(a, b)
+			q = new(big.Int).Sub(a, b)
+			if q.Cmp(bigOne) > 0 {
+				return p, q, nil
+			}
+			// n is prime, only the trivial factorisation exists
+			return nil, nil, ErrNotFound
+		}
+		b2.Add(b2, a).Add(b2, a).Add(b2, bigOne)
+		a.Add(a, bigOne)
+	}
+	return nil, nil, ErrNotFound
+}
+
+// PollardRho finds a non-trivial factor of n with Pollard's rho method and Floyd's cycle detection.
+// The sequence x -> x^2 + c mod n repeats modulo the smallest prime p of n after about sqrt(p) steps,
+// so factors of about 40 bits are found in seconds whatever the size of n.
+func PollardRho(n *big.Int, maxIterations int) (*big.Int, error) {
+	if n.Bit(0) == 0 {
+		return big.NewInt(2), nil
+	}
+
+	// The gcd is taken once for a batch of differences multiplied together, which is much cheaper
+	const batch = 100
+	for c := int64(1); c <= 10; c++ {
+		bigC := big.NewInt(c)
+		f := func(x *big.Int) *big.Int {
+			x.Mul(x, x).Add(x, bigC)
+			return x.Mod(x, n)
+		}
+
+		x, y := big.NewInt(2), big.NewInt(2)
+		product := big.NewInt(1)
+		diff, d := new(big.Int), new(big.Int)
+		xSaved, ySaved := new(big.Int), new(big.Int)
+
+		for i := 0; i < maxIterations; i += batch {
+			xSaved.Set(x)
+			ySaved.Set(y)
+			for j := 0; j < batch; j++ {
+				f(x)
+				f(f(y))
+				diff.Sub(x, y).Abs(diff)
+				product.Mul(product, diff).Mod(product, n)
+			}
+			d.GCD(nil, nil, product, n)
+			if d.Cmp(bigOne) == 0 {
+				continue
+			}
+
+			// Replay the batch one step at a time, the product may have hit several factors at once
+			x.Set(xSaved)
+			y.Set(ySaved)
+			for j := 0; j < batch; j++ {
+				f(x)
+				f(f(y))
+				diff.Sub(x, y).Abs(diff)
+				d.GCD(nil, nil, diff, n)
+				if d.Cmp(bigOne) != 0 {
+					break
+				}
+			}
+			if d.Cmp(n) != 0 {
+				return d, nil
+			}
+			// the cycle closed modulo every factor at once, try another polynomial
+			break
+		}
+	}
+	return nil, ErrNotFound
+}
@@ -0,0 +1,65 @@
+package attacks
+
+import (
+	"errors"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/rsa"
+	"math/big"
+)
+
+// Hastad recovers a message sent without padding to e recipients whose keys share the small public exponent e,
+// with Håstad's broadcast attack. The Chinese Remainder Theorem combines the ciphertexts into m^e mod N1*N2*...*Ne,
+// and as m is smaller than every modulus, m^e is smaller than their product: m is its plain integer e-th root.
+// ciphertexts[i] must be the encryption of the message under pubs[i].
+func Hastad(pubs []*rsa.PublicKey, ciphertexts []*big.Int) (*big.Int, error) {
+	if len(pubs) != len(ciphertexts) || len(pubs) == 0 {
+		return nil, errors.New("attacks: one ciphertext is needed for every key")
+	}
+	e := pubs[0].E
+	if len(pubs) < e {
+		return nil, ErrNotVulnerable
+	}
+
+	// Only e equations are needed
+	pubs, ciphertexts = pubs[:e], ciphertexts[:e]
+	moduli := make([]*big.Int, e)
+	for i, pub := range pubs {
+		if pub.E != e {
+			return nil, errors.New("attacks: the keys must share the same public exponent")
+		}
+		moduli[i] = pub.N
+	}
+
+	combined, err := crt(ciphertexts, moduli)
+	if err != nil {
+		return nil, err
+	}
+	m := nthRoot(combined, e)
+	if new(big.Int).Exp(m, big.NewInt(int64(e)), nil).Cmp(combined) != 0 {
+		// the message was padded or different for each recipient
+		return nil, ErrNotVulnerable
+	}
+	return m, nil
+}
+
+// crt returns the x modulo the product of moduli with x = remainders[i] mod moduli[i], the moduli being co-prime
+func crt(remainders, moduli []*big.Int) (*big.Int, error) {
+	product := big.NewInt(1)
+	for _, n := range moduli {
+		product.Mul(product, n)
+	}
+
+	x := new(big.Int)
+	for i, n := range moduli {
+		// x += r * M * (M^-1 mod n), with M the product of the other moduli
+		m := new(big.Int).Quo(product, n)
+		inv := new(big.Int).ModInverse(m, n)
+		if inv == nil {
+			// the moduli share a prime factor, which already breaks both keys
+			return nil, errors.New("attacks: the moduli are not co-prime")
+		}
+		term := new(big.Int).Mul(remainders[i], m)
+		term.Mul(term, inv)
+		x.Add(x, term)
+	}
+	return x.Mod(x, product), nil
+}
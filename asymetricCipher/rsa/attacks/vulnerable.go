@@ -0,0 +1,142 @@
+package attacks
+
+import (
+	"errors"
+	"github.com/EliriaT/CS-Labs/asymetricCipher/rsa"
+	"io"
+	"math/big"
+)
+
+// The functions below build the weak keys that each attack breaks, with the primes and keys of the rsa package.
+
+// primePair returns two different primes of bits/2 bits each
+func primePair(random io.Reader, bits int) (p, q *big.Int, err error) {
+	for {
+		if p, err = rsa.GeneratePrime(random, bits-bits/2); err != nil {
+			return nil, nil, err
+		}
+		if q, err = rsa.GeneratePrime(random, bits/2); err != nil {
+			return nil, nil, err
+		}
+		if p.Cmp(q) != 0 {
+			return p, q, nil
+		}
+	}
+}
+
+// nextPrime returns the smallest prime greater than n
+func nextPrime(random io.Reader, n *big.Int) *big.Int {
+	candidate := new(big.Int).Add(n, bigOne)
+	candidate.SetBit(candidate, 0, 1)
+	for !rsa.IsProbablePrime(candidate, 40, random) {
+		candidate.Add(candidate, big.NewInt(2))
+	}
+	return candidate
+}
+
+// WienerKey builds a key whose private exponent d is below N^(1/4)/3, and whose public exponent e is thus about as large as N.
+// The rsa package only takes a public exponent that fits an int, so the key is built by rsa.NewPrivateKey with the
+// exponents swapped: the small d is given as its public exponent, and the e of the weak key is its private exponent.
+func WienerKey(random io.Reader, bits int) (n, e, d *big.Int, err error) {
+	// d has a quarter of the bits of N, minus 2 bits for the factor 3, and must fit the int exponent of rsa.NewPrivateKey
+	dBits := bits/4 - 2
+	if dBits > 62 {
+		dBits = 62
+	}
+	if dBits < 2 {
+		return nil, nil, nil, errors.New("attacks: key size too small for a Wiener key")
+	}
+
+	for {
+		p, q, err := primePair(random, bits)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		buf := make([]byte, 8)
+		if _, err := io.ReadFull(random, buf); err != nil {
+			return nil, nil, nil, err
+		}
+		small := new(big.Int).SetBytes(buf)
+		small.Rsh(small, uint(64-dBits))
+		small.SetBit(small, 0, 1)
+		if small.Int64() < 3 {
+			continue
+		}
+
+		key, err := rsa.NewPrivateKey(p, q, int(small.Int64()))
+		if err != nil {
+			// d is not co-prime to phi, try other primes
+			continue
+		}
+		return key.N, key.D(), small, nil
+	}
+}
+
+// SmallExponentKeys builds count keys with the public exponent e, for a message broadcast to all of them
+func SmallExponentKeys(random io.Reader, bits, e, count int) ([]*rsa.PrivateKey, error) {
+	keys := make([]*rsa.PrivateKey, 0, count)
+	for len(keys) < count {
+		p, q, err := primePair(random, bits)
+		if err != nil {
+			return nil, err
+		}
+		key, err := rsa.NewPrivateKey(p, q, e)
+		if err != nil {
+			// e is not co-prime to phi, try other primes
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// CommonModulusKeys builds two keys sharing the same modulus, with the exponents e1 and e2
+func CommonModulusKeys(random io.Reader, bits, e1, e2 int) (*rsa.PrivateKey, *rsa.PrivateKey, error) {
+	if e1 == e2 {
+		return nil, nil, errors.New("attacks: the exponents must be different")
+	}
+	for {
+		p, q, err := primePair(random, bits)
+		if err != nil {
+			return nil, nil, err
+		}
+		key1, err1 := rsa.NewPrivateKey(p, q, e1)
+		key2, err2 := rsa.NewPrivateKey(p, q, e2)
+		if err1 == nil && err2 == nil {
+			return key1, key2, nil
+		}
+	}
+}
+
+// CloseFactorsKey builds a key whose second prime is the prime following the first one, which Fermat's method factors at once
+func CloseFactorsKey(random io.Reader, bits int) (*rsa.PrivateKey, error) {
+	for {
+		p, err := rsa.GeneratePrime(random, bits/2)
+		if err != nil {
+			return nil, err
+		}
+		key, err := rsa.NewPrivateKey(p, nextPrime(random, p), rsa.DefaultExponent)
+		if err == nil {
+			return key, nil
+		}
+	}
+}
+
+// SmallFactorKey builds a key with one prime of only factorBits bits, which Pollard's rho method finds quickly
+func SmallFactorKey(random io.Reader, bits, factorBits int) (*rsa.PrivateKey, error) {
+	for {
+		p, err := rsa.GeneratePrime(random, factorBits)
+		if err != nil {
+			return nil, err
+		}
+		q, err := rsa.GeneratePrime(random, bits-factorBits)
+		if err != nil {
+			return nil, err
+		}
+		key, err := rsa.NewPrivateKey(p, q, rsa.DefaultExponent)
+		if err == nil {
+			return key, nil
+		}
+	}
+}
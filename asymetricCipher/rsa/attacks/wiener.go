@@ -0,0 +1,54 @@
+package attacks
+
+import (
+	"math/big"
+)
+
+// Wiener recovers the private exponent d when d < N^(1/4)/3, with Wiener's continued fraction attack.
+// Since e*d = 1 + k*phi and phi is close to N, k/d is one of the convergents of the continued fraction of e/N.
+// Every convergent is tried: it gives a candidate phi, and phi is right when p and q, the roots of
+// x^2 - (N-phi+1)x + N, are integers. The exponent is a big.Int because such keys have an e as large as N.
+func Wiener(n, e *big.Int) (d, p, q *big.Int, err error) {
+	// h/k are the convergents of e/N, built from the partial quotients a of the continued fraction
+	num, den := new(big.Int).Set(e), new(big.Int).Set(n)
+	hPrev, h := big.NewInt(0), big.NewInt(1)
+	kPrev, k := big.NewInt(1), big.NewInt(0)
+	a, rem := new(big.Int), new(big.Int)
+
+	for den.Sign() != 0 {
+		a.QuoRem(num, den, rem)
+		num, den = den, new(big.Int).Set(rem)
+
+		hPrev, h = h, new(big.Int).Add(new(big.Int).Mul(a, h), hPrev)
+		kPrev, k = k, new(big.Int).Add(new(big.Int).Mul(a, k), kPrev)
+
+		// the candidate is k = h (the multiple of phi) and d = k (the denominator)
+		if h.Sign() == 0 {
+			continue
+		}
+		edMinusOne := new(big.Int).Mul(e, k)
+		edMinusOne.Sub(edMinusOne, bigOne)
+		phi, r := new(big.Int).QuoRem(edMinusOne, h, new(big.Int))
+		if r.Sign() != 0 {
+			continue
+		}
+
+		// p + q = N - phi + 1 and p*q = N
+		sum := new(big.Int).Sub(n, phi)
+		sum.Add(sum, bigOne)
+		disc := new(big.Int).Mul(sum, sum)
+		disc.Sub(disc, new(big.Int).Lsh(n, 2))
+		root, ok := isSquare(disc)
+		if !ok {
+			continue
+		}
+		p = new(big.Int).Add(sum, root)
+		p.Rsh(p, 1)
+		q = new(big.Int).Sub(sum, root)
+		q.Rsh(q, 1)
+		if q.Sign() > 0 && new(big.Int).Mul(p, q).Cmp(n) == 0 {
+			return new(big.Int).Set(k), p, q, nil
+		}
+	}
+	return nil, nil, nil, ErrNotVulnerable
+}
@@ -0,0 +1,205 @@
+package rsa
+
+import (
+	stdrsa "crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// PEM block types read and written by this package
+const (
+	pemPKCS1PrivateKey     = "RSA PRIVATE KEY"
+	pemPKCS8PrivateKey     = "PRIVATE KEY"
+	pemEncryptedPrivateKey = "ENCRYPTED PRIVATE KEY"
+	pemPKCS1PublicKey      = "RSA PUBLIC KEY"
+	pemPKIXPublicKey       = "PUBLIC KEY"
+)
+
+var (
+	ErrNotRSAKey       = errors.New("rsa: key is not an RSA key")
+	ErrNoPEMBlock      = errors.New("rsa: no PEM block found")
+	ErrPassphrase      = errors.New("rsa: private key is encrypted, a passphrase is required")
+	ErrUnsupportedPEM  = errors.New("rsa: unsupported PEM block type")
+	ErrMultiPrimeKey   = errors.New("rsa: keys with more than two primes are not supported")
+	ErrLegacyEncrypted = errors.New("rsa: legacy encrypted PEM is not supported, convert the key to encrypted PKCS #8")
+)
+
+// toStdlib converts the key to crypto/rsa, whose x509 encoders follow the DER structures of PKCS #1
+func (pk *PrivateKey) toStdlib() *stdrsa.PrivateKey {
+	key := &stdrsa.PrivateKey{
+		PublicKey: *pk.PublicKey.toStdlib(),
+		D:         new(big.Int).Set(pk.d),
+		Primes:    []*big.Int{new(big.Int).Set(pk.p), new(big.Int).Set(pk.q)},
+	}
+	key.Precompute()
+	return key
+}
+
+func (pk *PublicKey) toStdlib() *stdrsa.PublicKey {
+	return &stdrsa.PublicKey{N: new(big.Int).Set(pk.N), E: pk.E}
+}
+
+// fromStdlib converts a decoded key, keeping its private exponent, which may have been computed modulo lambda(N) instead of phi(N)
+func fromStdlib(key *stdrsa.PrivateKey) (*PrivateKey, error) {
+	if len(key.Primes) != 2 {
+		return nil, ErrMultiPrimeKey
+	}
+	if err := key.Validate(); err != nil {
+		return nil, fmt.Errorf("rsa: invalid private key: %w", err)
+	}
+
+	result, err := NewPrivateKey(key.Primes[0], key.Primes[1], key.E)
+	if err != nil {
+		return nil, err
+	}
+	result.d = new(big.Int).Set(key.D)
+	result.dP = new(big.Int).Mod(key.D, new(big.Int).Sub(result.p, bigOne))
+	result.dQ = new(big.Int).Mod(key.D, new(big.Int).Sub(result.q, bigOne))
+	return result, nil
+}
+
+func publicFromStdlib(key interface{}) (*PublicKey, error) {
+	pub, ok := key.(*stdrsa.PublicKey)
+	if !ok {
+		return nil, ErrNotRSAKey
+	}
+	return &PublicKey{N: pub.N, E: pub.E}, nil
+}
+
+// MarshalPKCS1PrivateKey returns the PKCS #1, ASN.1 DER form of the private key
+func MarshalPKCS1PrivateKey(key *PrivateKey) []byte {
+	return x509.MarshalPKCS1PrivateKey(key.toStdlib())
+}
+
+// ParsePKCS1PrivateKey parses a private key in PKCS #1, ASN.1 DER form
+func ParsePKCS1PrivateKey(der []byte) (*PrivateKey, error) {
+	key, err := x509.ParsePKCS1PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	return fromStdlib(key)
+}
+
+// MarshalPKCS8PrivateKey returns the PKCS #8, ASN.1 DER form of the private key
+func MarshalPKCS8PrivateKey(key *PrivateKey) ([]byte, error) {
+	return x509.MarshalPKCS8PrivateKey(key.toStdlib())
+}
+
+// ParsePKCS8PrivateKey parses an unencrypted private key in PKCS #8, ASN.1 DER form
+func ParsePKCS8PrivateKey(der []byte) (*PrivateKey, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*stdrsa.PrivateKey)
+	if !ok {
+		return nil, ErrNotRSAKey
+	}
+	return fromStdlib(rsaKey)
+}
+
+// MarshalPKCS1PublicKey returns the PKCS #1, ASN.1 DER form of the public key
+func MarshalPKCS1PublicKey(pub *PublicKey) []byte {
+	return x509.MarshalPKCS1PublicKey(pub.toStdlib())
+}
+
+// ParsePKCS1PublicKey parses a public key in PKCS #1, ASN.1 DER form
+func ParsePKCS1PublicKey(der []byte) (*PublicKey, error) {
+	key, err := x509.ParsePKCS1PublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	return publicFromStdlib(key)
+}
+
+// MarshalPKIXPublicKey returns the PKIX (SubjectPublicKeyInfo), ASN.1 DER form of the public key
+func MarshalPKIXPublicKey(pub *PublicKey) ([]byte, error) {
+	return x509.MarshalPKIXPublicKey(pub.toStdlib())
+}
+
+// ParsePKIXPublicKey parses a public key in PKIX (SubjectPublicKeyInfo), ASN.1 DER form
+func ParsePKIXPublicKey(der []byte) (*PublicKey, error) {
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	return publicFromStdlib(key)
+}
+
+// EncodePrivateKeyPEM returns the private key as a PKCS #8 PEM block. If passphrase is not empty the key is
+// encrypted with PBES2 (PBKDF2 with HMAC-SHA256 and AES-256-CBC), as "openssl pkcs8 -topk8 -v2 aes-256-cbc" does.
+func EncodePrivateKeyPEM(key *PrivateKey, passphrase []byte) ([]byte, error) {
+	der, err := MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return pem.EncodeToMemory(&pem.Block{Type: pemPKCS8PrivateKey, Bytes: der}), nil
+	}
+
+	encrypted, err := encryptPKCS8(der, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemEncryptedPrivateKey, Bytes: encrypted}), nil
+}
+
+// DecodePrivateKeyPEM reads the first private key PEM block of data, in PKCS #1 or PKCS #8 form,
+// decrypting it with passphrase if it is an encrypted PKCS #8 key
+func DecodePrivateKeyPEM(data []byte, passphrase []byte) (*PrivateKey, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, ErrNoPEMBlock
+		}
+
+		switch block.Type {
+		case pemPKCS1PrivateKey:
+			if _, ok := block.Headers["DEK-Info"]; ok {
+				return nil, ErrLegacyEncrypted
+			}
+			return ParsePKCS1PrivateKey(block.Bytes)
+		case pemPKCS8PrivateKey:
+			return ParsePKCS8PrivateKey(block.Bytes)
+		case pemEncryptedPrivateKey:
+			if len(passphrase) == 0 {
+				return nil, ErrPassphrase
+			}
+			der, err := decryptPKCS8(block.Bytes, passphrase)
+			if err != nil {
+				return nil, err
+			}
+			return ParsePKCS8PrivateKey(der)
+		}
+		// other blocks, like EC parameters, are skipped
+	}
+}
+
+// EncodePublicKeyPEM returns the public key as a PKIX "PUBLIC KEY" PEM block
+func EncodePublicKeyPEM(pub *PublicKey) ([]byte, error) {
+	der, err := MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemPKIXPublicKey, Bytes: der}), nil
+}
+
+// DecodePublicKeyPEM reads the first PEM block of data, a PKIX or PKCS #1 public key
+func DecodePublicKeyPEM(data []byte) (*PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrNoPEMBlock
+	}
+
+	switch block.Type {
+	case pemPKIXPublicKey:
+		return ParsePKIXPublicKey(block.Bytes)
+	case pemPKCS1PublicKey:
+		return ParsePKCS1PublicKey(block.Bytes)
+	}
+	return nil, ErrUnsupportedPEM
+}
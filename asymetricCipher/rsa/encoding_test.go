@@ -0,0 +1,141 @@
+package rsa
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// The keys of testdata were generated by OpenSSL 3:
+//
+//	openssl genrsa -traditional -out pkcs1.pem 2048
+//	openssl pkcs8 -topk8 -nocrypt -in pkcs1.pem -out pkcs8.pem
+//	openssl pkcs8 -topk8 -v2 aes-256-cbc -v2prf hmacWithSHA256 -passout pass:testpassphrase -in pkcs1.pem -out pkcs8_aes256_sha256.pem
+//	openssl pkcs8 -topk8 -v2 aes-128-cbc -v2prf hmacWithSHA1 -passout pass:testpassphrase -in pkcs1.pem -out pkcs8_aes128_sha1.pem
+//	openssl rsa -in pkcs1.pem -pubout -out public_pkix.pem
+//	openssl rsa -in pkcs1.pem -RSAPublicKey_out -out public_pkcs1.pem
+const testPassphrase = "testpassphrase"
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDecodeOpenSSLKeys(t *testing.T) {
+	reference, err := DecodePrivateKeyPEM(readTestdata(t, "pkcs1.pem"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"pkcs8.pem", "pkcs8_aes256_sha256.pem", "pkcs8_aes128_sha1.pem"} {
+		key, err := DecodePrivateKeyPEM(readTestdata(t, name), []byte(testPassphrase))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if key.N.Cmp(reference.N) != 0 || key.d.Cmp(reference.d) != 0 {
+			t.Fatalf("%s: the key differs from pkcs1.pem", name)
+		}
+	}
+
+	for _, name := range []string{"public_pkix.pem", "public_pkcs1.pem"} {
+		pub, err := DecodePublicKeyPEM(readTestdata(t, name))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if pub.N.Cmp(reference.N) != 0 || pub.E != reference.E {
+			t.Fatalf("%s: the key differs from pkcs1.pem", name)
+		}
+	}
+
+	encrypted := readTestdata(t, "pkcs8_aes256_sha256.pem")
+	if _, err := DecodePrivateKeyPEM(encrypted, nil); err != ErrPassphrase {
+		t.Fatalf("decoding without a passphrase = %v, want ErrPassphrase", err)
+	}
+	if _, err := DecodePrivateKeyPEM(encrypted, []byte("wrong")); err != ErrWrongPassphrase {
+		t.Fatalf("decoding with a wrong passphrase = %v, want ErrWrongPassphrase", err)
+	}
+}
+
+// TestOpenSSLReadsEncodedKeys gives the keys written by this package to the openssl command, when it is installed
+func TestOpenSSLReadsEncodedKeys(t *testing.T) {
+	openssl, err := exec.LookPath("openssl")
+	if err != nil {
+		t.Skip("openssl is not installed")
+	}
+	key, err := DecodePrivateKeyPEM(readTestdata(t, "pkcs1.pem"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, passphrase := range []string{"", testPassphrase} {
+		encoded, err := EncodePrivateKeyPEM(key, []byte(passphrase))
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(t.TempDir(), "key.pem")
+		if err := os.WriteFile(path, encoded, 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		args := []string{"rsa", "-in", path, "-traditional"}
+		if passphrase != "" {
+			args = append(args, "-passin", "pass:"+passphrase)
+		}
+		out, err := exec.Command(openssl, args...).Output()
+		if err != nil {
+			t.Fatalf("openssl can not read the key encrypted with %q: %v", passphrase, err)
+		}
+		if !bytes.Equal(bytes.TrimSpace(out), bytes.TrimSpace(readTestdata(t, "pkcs1.pem"))) {
+			t.Fatalf("openssl read another key from the key encrypted with %q", passphrase)
+		}
+	}
+}
+
+func TestIterationCountBounds(t *testing.T) {
+	for _, iterations := range []int{0, -1, maxPBKDF2Iterations + 1, 1 << 40} {
+		der := encryptedKeyWithIterations(t, iterations)
+		if _, err := decryptPKCS8(der, []byte(testPassphrase)); err != ErrIterationCount {
+			t.Fatalf("%d iterations: %v, want ErrIterationCount", iterations, err)
+		}
+	}
+}
+
+// encryptedKeyWithIterations builds an EncryptedPrivateKeyInfo claiming iterations PBKDF2 iterations
+func encryptedKeyWithIterations(t *testing.T, iterations int) []byte {
+	t.Helper()
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:           make([]byte, pbkdf2SaltSize),
+		IterationCount: iterations,
+		PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ivParam, err := asn1.Marshal(make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemeParams, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParam}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm:     pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: schemeParams}},
+		EncryptedData: make([]byte, 32),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
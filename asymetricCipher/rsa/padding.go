@@ -0,0 +1,219 @@
+package rsa
+
+import (
+	"crypto/subtle"
+	"errors"
+	"hash"
+	"io"
+	"math/big"
+)
+
+// Different types of error returned by the padded encryption functions
+var (
+	ErrMessageTooLong = errors.New("rsa: message too long for RSA key size")
+	ErrDecryption     = errors.New("rsa: decryption error")
+)
+
+// encrypt computes m^e mod N
+func encrypt(pub *PublicKey, m *big.Int) *big.Int {
+	return new(big.Int).Exp(m, big.NewInt(int64(pub.E)), pub.N)
+}
+
+// decrypt computes c^d mod N with the Chinese Remainder Theorem: c^dP mod p and c^dQ mod q are recombined
+// with Garner's formula, which is about four times faster than one exponentiation modulo N.
+// The result is checked by encrypting it again, so that a computation fault can not leak the factors of N.
+func decrypt(priv *PrivateKey, c *big.Int) (*big.Int, error) {
+	if c.Sign() < 0 || c.Cmp(priv.N) >= 0 {
+		return nil, ErrDecryption
+	}
+
+	m1 := new(big.Int).Exp(c, priv.dP, priv.p)
+	m2 := new(big.Int).Exp(c, priv.dQ, priv.q)
+
+	// h = qInv * (m1 - m2) mod p, m = m2 + h*q
+	h := m1.Sub(m1, m2)
+	h.Mul(h, priv.qInv)
+	h.Mod(h, priv.p)
+	m := h.Mul(h, priv.q)
+	m.Add(m, m2)
+
+	if encrypt(&priv.PublicKey, m).Cmp(c) != 0 {
+		return nil, ErrDecryption
+	}
+	return m, nil
+}
+
+// EncryptOAEP encrypts msg with RSA-OAEP as defined in PKCS #1 v2.2, hash being used both for the label and MGF1.
+// The message can be at most k-2*hLen-2 bytes long, k being the modulus size.
+func EncryptOAEP(hash hash.Hash, random io.Reader, pub *PublicKey, msg []byte, label []byte) ([]byte, error) {
+	hash.Reset()
+	k := pub.Size()
+	hLen := hash.Size()
+	if len(msg) > k-2*hLen-2 {
+		return nil, ErrMessageTooLong
+	}
+
+	hash.Write(label)
+	lHash := hash.Sum(nil)
+	hash.Reset()
+
+	// EM = 0x00 || maskedSeed || maskedDB, with DB = lHash || PS || 0x01 || M
+	em := make([]byte, k)
+	seed := em[1 : 1+hLen]
+	db := em[1+hLen:]
+	copy(db, lHash)
+	db[len(db)-len(msg)-1] = 1
+	copy(db[len(db)-len(msg):], msg)
+
+	if _, err := io.ReadFull(random, seed); err != nil {
+		return nil, err
+	}
+
+	mgf1XOR(db, hash, seed)
+	mgf1XOR(seed, hash, db)
+
+	c := encrypt(pub, new(big.Int).SetBytes(em))
+	return c.FillBytes(make([]byte, k)), nil
+}
+
+// DecryptOAEP decrypts a RSA-OAEP ciphertext. The same hash and label as for the encryption must be given.
+// All the checks on the decoded block are done in constant time, and fail with the same ErrDecryption.
+func DecryptOAEP(hash hash.Hash, priv *PrivateKey, ciphertext []byte, label []byte) ([]byte, error) {
+	hash.Reset()
+	k := priv.Size()
+	hLen := hash.Size()
+	if len(ciphertext) != k || k < 2*hLen+2 {
+		return nil, ErrDecryption
+	}
+
+	m, err := decrypt(priv, new(big.Int).SetBytes(ciphertext))
+	if err != nil {
+		return nil, err
+	}
+
+	hash.Write(label)
+	lHash := hash.Sum(nil)
+	hash.Reset()
+
+	em := m.FillBytes(make([]byte, k))
+	firstByteIsZero := subtle.ConstantTimeByteEq(em[0], 0)
+	seed := em[1 : 1+hLen]
+	db := em[1+hLen:]
+
+	mgf1XOR(seed, hash, db)
+	mgf1XOR(db, hash, seed)
+
+	lHashGood := subtle.ConstantTimeCompare(lHash, db[:hLen])
+
+	// The rest of DB must be zero bytes followed by 0x01, the message starts after it
+	lookingForIndex, index, invalid := 1, 0, 0
+	rest := db[hLen:]
+	for i := 0; i < len(rest); i++ {
+		equals0 := subtle.ConstantTimeByteEq(rest[i], 0)
+		equals1 := subtle.ConstantTimeByteEq(rest[i], 1)
+		index = subtle.ConstantTimeSelect(lookingForIndex&equals1, i, index)
+		lookingForIndex = subtle.ConstantTimeSelect(equals1, 0, lookingForIndex)
+		invalid = subtle.ConstantTimeSelect(lookingForIndex&^equals0, 1, invalid)
+	}
+
+	if firstByteIsZero&lHashGood&^invalid&^lookingForIndex != 1 {
+		return nil, ErrDecryption
+	}
+	return append([]byte(nil), rest[index+1:]...), nil
+}
+
+// mgf1XOR XORs out with the MGF1 mask generated from seed
+func mgf1XOR(out []byte, hash hash.Hash, seed []byte) {
+	var counter [4]byte
+	done := 0
+	for done < len(out) {
+		hash.Write(seed)
+		hash.Write(counter[:])
+		digest := hash.Sum(nil)
+		hash.Reset()
+
+		for i := 0; i < len(digest) && done < len(out); i++ {
+			out[done] ^= digest[i]
+			done++
+		}
+		incCounter(&counter)
+	}
+}
+
+func incCounter(c *[4]byte) {
+	for i := 3; i >= 0; i-- {
+		c[i]++
+		if c[i] != 0 {
+			return
+		}
+	}
+}
+
+// EncryptPKCS1v15 encrypts msg with the RSAES-PKCS1-v1_5 scheme. The message can be at most k-11 bytes long.
+// This scheme is kept for interoperability, OAEP should be preferred for new messages.
+func EncryptPKCS1v15(random io.Reader, pub *PublicKey, msg []byte) ([]byte, error) {
+	k := pub.Size()
+	if len(msg) > k-11 {
+		return nil, ErrMessageTooLong
+	}
+
+	// EM = 0x00 || 0x02 || PS || 0x00 || M, PS being non-zero random bytes
+	em := make([]byte, k)
+	em[1] = 2
+	ps := em[2 : len(em)-len(msg)-1]
+	if err := nonZeroRandomBytes(ps, random); err != nil {
+		return nil, err
+	}
+	copy(em[len(em)-len(msg):], msg)
+
+	c := encrypt(pub, new(big.Int).SetBytes(em))
+	return c.FillBytes(make([]byte, k)), nil
+}
+
+// DecryptPKCS1v15 decrypts a RSAES-PKCS1-v1_5 ciphertext, checking the padding in constant time
+func DecryptPKCS1v15(priv *PrivateKey, ciphertext []byte) ([]byte, error) {
+	k := priv.Size()
+	if len(ciphertext) != k || k < 11 {
+		return nil, ErrDecryption
+	}
+
+	m, err := decrypt(priv, new(big.Int).SetBytes(ciphertext))
+	if err != nil {
+		return nil, err
+	}
+	em := m.FillBytes(make([]byte, k))
+
+	firstByteIsZero := subtle.ConstantTimeByteEq(em[0], 0)
+	secondByteIsTwo := subtle.ConstantTimeByteEq(em[1], 2)
+
+	// The message starts after the first zero byte following the padding string
+	lookingForIndex, index := 1, 0
+	for i := 2; i < len(em); i++ {
+		equals0 := subtle.ConstantTimeByteEq(em[i], 0)
+		index = subtle.ConstantTimeSelect(lookingForIndex&equals0, i, index)
+		lookingForIndex = subtle.ConstantTimeSelect(equals0, 0, lookingForIndex)
+	}
+
+	// The padding string must be at least 8 bytes long
+	validPS := subtle.ConstantTimeLessOrEq(2+8, index)
+
+	if firstByteIsZero&secondByteIsTwo&^lookingForIndex&validPS != 1 {
+		return nil, ErrDecryption
+	}
+	return append([]byte(nil), em[index+1:]...), nil
+}
+
+// nonZeroRandomBytes fills s with random bytes, none of them being zero
+func nonZeroRandomBytes(s []byte, random io.Reader) error {
+	if _, err := io.ReadFull(random, s); err != nil {
+		return err
+	}
+	for i := 0; i < len(s); i++ {
+		for s[i] == 0 {
+			if _, err := io.ReadFull(random, s[i:i+1]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package rsa
+
+import (
+	"bytes"
+	"crypto/rand"
+	stdrsa "crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"hash"
+	"sync"
+	"testing"
+)
+
+var (
+	testKeyOnce sync.Once
+	testKeyData *PrivateKey
+	testKeyErr  error
+)
+
+// testKey returns a 2048-bit key generated once by GenerateKey and shared by the tests
+func testKey(tb testing.TB) *PrivateKey {
+	tb.Helper()
+	testKeyOnce.Do(func() {
+		testKeyData, testKeyErr = GenerateKey(rand.Reader, DefaultKeySize)
+	})
+	if testKeyErr != nil {
+		tb.Fatal(testKeyErr)
+	}
+	return testKeyData
+}
+
+func TestOAEPInteroperability(t *testing.T) {
+	key := testKey(t)
+	std := key.toStdlib()
+
+	for _, newHash := range []func() hash.Hash{sha1.New, sha256.New} {
+		for _, label := range [][]byte{nil, []byte("label")} {
+			msg := []byte("an OAEP message")
+
+			ciphertext, err := EncryptOAEP(newHash(), rand.Reader, &key.PublicKey, msg, label)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decrypted, err := stdrsa.DecryptOAEP(newHash(), nil, std, ciphertext, label)
+			if err != nil {
+				t.Fatalf("crypto/rsa can not decrypt: %v", err)
+			}
+			if !bytes.Equal(decrypted, msg) {
+				t.Fatalf("crypto/rsa decrypted %q", decrypted)
+			}
+
+			ciphertext, err = stdrsa.EncryptOAEP(newHash(), rand.Reader, &std.PublicKey, msg, label)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decrypted, err = DecryptOAEP(newHash(), key, ciphertext, label)
+			if err != nil {
+				t.Fatalf("can not decrypt crypto/rsa: %v", err)
+			}
+			if !bytes.Equal(decrypted, msg) {
+				t.Fatalf("decrypted %q", decrypted)
+			}
+
+			if _, err := DecryptOAEP(newHash(), key, ciphertext, []byte("other label")); err != ErrDecryption {
+				t.Fatalf("DecryptOAEP with a wrong label = %v", err)
+			}
+		}
+	}
+}
+
+func TestPKCS1v15Interoperability(t *testing.T) {
+	key := testKey(t)
+	std := key.toStdlib()
+	msg := []byte("a PKCS #1 v1.5 message")
+
+	ciphertext, err := EncryptPKCS1v15(rand.Reader, &key.PublicKey, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := stdrsa.DecryptPKCS1v15(nil, std, ciphertext)
+	if err != nil {
+		t.Fatalf("crypto/rsa can not decrypt: %v", err)
+	}
+	if !bytes.Equal(decrypted, msg) {
+		t.Fatalf("crypto/rsa decrypted %q", decrypted)
+	}
+
+	ciphertext, err = stdrsa.EncryptPKCS1v15(rand.Reader, &std.PublicKey, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err = DecryptPKCS1v15(key, ciphertext)
+	if err != nil {
+		t.Fatalf("can not decrypt crypto/rsa: %v", err)
+	}
+	if !bytes.Equal(decrypted, msg) {
+		t.Fatalf("decrypted %q", decrypted)
+	}
+}
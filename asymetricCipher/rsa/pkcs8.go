@@ -0,0 +1,176 @@
+package rsa
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"github.com/EliriaT/CS-Labs/streamBlockCipher/padding"
+	"golang.org/x/crypto/pbkdf2"
+	"hash"
+)
+
+const (
+	pbkdf2Iterations = 600000
+	pbkdf2SaltSize   = 16
+	// maxPBKDF2Iterations bounds the iteration count read from a key, so that a crafted key can not hang the loader
+	maxPBKDF2Iterations = 10000000
+)
+
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+var (
+	ErrUnsupportedEncryption = errors.New("rsa: unsupported private key encryption, only PBES2 with PBKDF2 and AES-CBC is supported")
+	ErrWrongPassphrase       = errors.New("rsa: wrong passphrase or corrupted private key")
+	ErrIterationCount        = errors.New("rsa: PBKDF2 iteration count of the private key is out of range")
+)
+
+// encryptedPrivateKeyInfo is the EncryptedPrivateKeyInfo structure of RFC 5208
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// pbes2Params is the PBES2-params structure of RFC 8018
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+// pbkdf2Params is the PBKDF2-params structure of RFC 8018, the key length being left out
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// encryptPKCS8 wraps a PKCS #8 private key in an EncryptedPrivateKeyInfo using PBKDF2-HMAC-SHA256 and AES-256-CBC
+func encryptPKCS8(der []byte, passphrase []byte) ([]byte, error) {
+	salt := make([]byte, pbkdf2SaltSize)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	key := pbkdf2.Key(passphrase, salt, pbkdf2Iterations, 32, sha256.New)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	padded, err := padding.PKCS7.Pad(der, aes.BlockSize)
+	if err != nil {
+		return nil, err
+	}
+	encrypted := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, padded)
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: pbkdf2Iterations,
+		PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ivParam, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+	schemeParams, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParam}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm:     pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: schemeParams}},
+		EncryptedData: encrypted,
+	})
+}
+
+// decryptPKCS8 unwraps an EncryptedPrivateKeyInfo encrypted with PBES2, PBKDF2 (HMAC-SHA1 or HMAC-SHA256) and AES-CBC
+func decryptPKCS8(der []byte, passphrase []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if rest, err := asn1.Unmarshal(der, &info); err != nil || len(rest) != 0 {
+		return nil, errors.New("rsa: invalid encrypted private key")
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, ErrUnsupportedEncryption
+	}
+
+	var scheme pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &scheme); err != nil {
+		return nil, ErrUnsupportedEncryption
+	}
+	if !scheme.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, ErrUnsupportedEncryption
+	}
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(scheme.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, ErrUnsupportedEncryption
+	}
+	if kdf.IterationCount <= 0 || kdf.IterationCount > maxPBKDF2Iterations {
+		return nil, ErrIterationCount
+	}
+
+	var prf func() hash.Hash
+	switch {
+	case len(kdf.PRF.Algorithm) == 0 || kdf.PRF.Algorithm.Equal(oidHMACWithSHA1):
+		prf = sha1.New
+	case kdf.PRF.Algorithm.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	default:
+		return nil, ErrUnsupportedEncryption
+	}
+
+	var keySize int
+	switch {
+	case scheme.EncryptionScheme.Algorithm.Equal(oidAES128CBC):
+		keySize = 16
+	case scheme.EncryptionScheme.Algorithm.Equal(oidAES192CBC):
+		keySize = 24
+	case scheme.EncryptionScheme.Algorithm.Equal(oidAES256CBC):
+		keySize = 32
+	default:
+		return nil, ErrUnsupportedEncryption
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(scheme.EncryptionScheme.Parameters.FullBytes, &iv); err != nil || len(iv) != aes.BlockSize {
+		return nil, ErrUnsupportedEncryption
+	}
+
+	if len(info.EncryptedData) == 0 || len(info.EncryptedData)%aes.BlockSize != 0 {
+		return nil, ErrWrongPassphrase
+	}
+	key := pbkdf2.Key(passphrase, kdf.Salt, kdf.IterationCount, keySize, prf)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	decrypted := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, info.EncryptedData)
+
+	plain, err := padding.PKCS7.Unpad(decrypted, aes.BlockSize)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plain, nil
+}
@@ -1,3 +1,125 @@
 package rsa
 
-var Primes = []int{37, 41, 53, 59, 67, 71, 97, 101, 127, 149, 179, 191, 223, 227, 251, 257, 269, 307}
+import (
+	"errors"
+	"io"
+	"math/big"
+)
+
+// smallPrimes are used to discard most composite candidates before running Miller-Rabin
+var smallPrimes = sieve(2000)
+
+// millerRabinRounds gives an error probability below 2^-80 for random candidates of cryptographic size
+const millerRabinRounds = 40
+
+var (
+	bigOne = big.NewInt(1)
+	bigTwo = big.NewInt(2)
+)
+
+// sieve returns all the primes smaller than limit, using the sieve of Eratosthenes
+func sieve(limit int) []uint64 {
+	composite := make([]bool, limit)
+	var primes []uint64
+	for i := 2; i < limit; i++ {
+		if composite[i] {
+			continue
+		}
+		primes = append(primes, uint64(i))
+		for j := i * i; j < limit; j += i {
+			composite[j] = true
+		}
+	}
+	return primes
+}
+
+// GeneratePrime returns a random probable prime of exactly bits bits, the two top bits being set
+// so that the product of two such primes has exactly twice as many bits.
+func GeneratePrime(random io.Reader, bits int) (*big.Int, error) {
+	if bits < 16 {
+		return nil, errors.New("rsa: prime size must be at least 16 bits")
+	}
+
+	buf := make([]byte, (bits+7)/8)
+	candidate := new(big.Int)
+	for {
+		if _, err := io.ReadFull(random, buf); err != nil {
+			return nil, err
+		}
+
+		// Clear the bits above the requested size, then set the two top bits and make the candidate odd
+		excess := uint(len(buf)*8 - bits)
+		buf[0] &= byte(0xff >> excess)
+		if excess < 7 {
+			buf[0] |= 0xc0 >> excess
+		} else {
+			buf[0] |= 0x01
+			buf[1] |= 0x80
+		}
+		buf[len(buf)-1] |= 1
+
+		candidate.SetBytes(buf)
+		if IsProbablePrime(candidate, millerRabinRounds, random) {
+			return candidate, nil
+		}
+	}
+}
+
+// IsProbablePrime checks n with trial division by small primes and then rounds of the Miller-Rabin test,
+// the bases being drawn from random. A composite passes with a probability of at most 4^-rounds.
+func IsProbablePrime(n *big.Int, rounds int, random io.Reader) bool {
+	if n.Cmp(bigTwo) < 0 {
+		return false
+	}
+
+	mod := new(big.Int)
+	for _, p := range smallPrimes {
+		prime := new(big.Int).SetUint64(p)
+		if n.Cmp(prime) == 0 {
+			return true
+		}
+		if mod.Mod(n, prime).Sign() == 0 {
+			return false
+		}
+	}
+
+	return millerRabin(n, rounds, random)
+}
+
+// millerRabin writes n-1 as 2^s*d and checks for every random base a that a^d = 1 or a^(2^r*d) = -1 mod n for some r < s
+func millerRabin(n *big.Int, rounds int, random io.Reader) bool {
+	nMinusOne := new(big.Int).Sub(n, bigOne)
+	s := nMinusOne.TrailingZeroBits()
+	d := new(big.Int).Rsh(nMinusOne, s)
+
+	// bases are picked in [2, n-2]
+	baseRange := new(big.Int).Sub(n, big.NewInt(3))
+	buf := make([]byte, (n.BitLen()+7)/8)
+	a, x := new(big.Int), new(big.Int)
+
+nextRound:
+	for i := 0; i < rounds; i++ {
+		if _, err := io.ReadFull(random, buf); err != nil {
+			return false
+		}
+		a.SetBytes(buf)
+		a.Mod(a, baseRange)
+		a.Add(a, bigTwo)
+
+		x.Exp(a, d, n)
+		if x.Cmp(bigOne) == 0 || x.Cmp(nMinusOne) == 0 {
+			continue
+		}
+		for r := uint(1); r < s; r++ {
+			x.Mul(x, x).Mod(x, n)
+			if x.Cmp(nMinusOne) == 0 {
+				continue nextRound
+			}
+			if x.Cmp(bigOne) == 0 {
+				return false
+			}
+		}
+		return false
+	}
+	return true
+}
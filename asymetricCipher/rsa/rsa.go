@@ -1,79 +1,140 @@
 package rsa
 
 import (
+	"crypto/rand"
+	"errors"
 	"fmt"
+	"io"
 	"math/big"
-	"math/rand"
+)
+
+const (
+	// DefaultKeySize is the modulus size in bits used by NewRSA
+	DefaultKeySize = 2048
+	MinKeySize     = 1024
+	MaxKeySize     = 4096
+	// DefaultExponent is the public exponent of the generated keys
+	DefaultExponent = 65537
 )
 
 // A PublicKey represents the public part of an RSA key.
 type PublicKey struct {
-	N   int64 // modulus
-	E   int64 // public exponent
-	phi int64
+	N *big.Int // modulus
+	E int      // public exponent
 }
 
-func (pk *PublicKey) setPublicKey() {
+// Size returns the modulus size in bytes
+func (pk *PublicKey) Size() int {
+	return (pk.N.BitLen() + 7) / 8
+}
 
-	lenPrimes := len(Primes)
-	pInd := rand.Intn(lenPrimes)
-	qInd := rand.Intn(lenPrimes)
-	for qInd == pInd {
-		qInd = rand.Intn(lenPrimes)
+// A PrivateKey represents an RSA key
+type PrivateKey struct {
+	PublicKey          // public part.
+	d         *big.Int // private exponent
+	phi       *big.Int
+	p, q      *big.Int // prime factors of N
+
+	// values precomputed for decryption with the Chinese Remainder Theorem
+	dP   *big.Int // d mod (p-1)
+	dQ   *big.Int // d mod (q-1)
+	qInv *big.Int // q^-1 mod p
+}
+
+// GenerateKey generates an RSA key pair with a modulus of bits bits, from 1024 to 4096, and the public exponent 65537.
+// The primes are found with the Miller-Rabin test, using random as the source of randomness.
+func GenerateKey(random io.Reader, bits int) (*PrivateKey, error) {
+	if bits < MinKeySize || bits > MaxKeySize {
+		return nil, fmt.Errorf("rsa: key size must be from %d to %d bits, got %d", MinKeySize, MaxKeySize, bits)
 	}
-	p := Primes[pInd]
-	q := Primes[qInd]
-
-	pk.N = int64(p) * int64(q)
-	pk.E = 2
-	pk.phi = (int64(p) - 1) * (int64(q) - 1)
-	for pk.E < pk.phi {
-		// e must be co-prime to phi and smaller than phi.
-		if gcd(pk.E, pk.phi) == 1 && gcd(pk.E, pk.N) == 1 {
-			break
-		} else {
-			pk.E++
+
+	for {
+		p, err := GeneratePrime(random, bits-bits/2)
+		if err != nil {
+			return nil, err
+		}
+		q, err := GeneratePrime(random, bits/2)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := NewPrivateKey(p, q, DefaultExponent)
+		if err != nil {
+			// e is not co-prime to phi or p equals q, try other primes
+			continue
+		}
+		if key.N.BitLen() != bits {
+			continue
 		}
+		return key, nil
 	}
 }
 
-// A PrivateKey represents an RSA key
-type PrivateKey struct {
-	PublicKey       // public part.
-	d         int64 // private exponent
-}
+// NewPrivateKey builds the RSA key of the primes p and q with the public exponent e.
+// The primality of p and q is not checked.
+func NewPrivateKey(p, q *big.Int, e int) (*PrivateKey, error) {
+	if p.Cmp(q) == 0 {
+		return nil, errors.New("rsa: p and q must be different")
+	}
+	if e < 3 {
+		return nil, errors.New("rsa: public exponent must be at least 3")
+	}
 
-func (pk *PrivateKey) setPrKey(publicKey PublicKey) error {
-	pk.PublicKey = publicKey
+	n := new(big.Int).Mul(p, q)
+	phi := new(big.Int).Mul(new(big.Int).Sub(p, bigOne), new(big.Int).Sub(q, bigOne))
 
 	//d is modular inverse of e mod phi
-	pk.d = modInverse(pk.E, pk.phi)
-	if pk.d == 0 {
-		return fmt.Errorf("Can not set private key.")
+	d := modInverse(big.NewInt(int64(e)), phi)
+	if d == nil {
+		return nil, fmt.Errorf("Can not set private key.")
 	}
-	return nil
-}
 
-func gcd(a int64, b int64) int64 {
-	var temp int64
-	for {
-		temp = a % b
-		if temp == 0 {
-			return b
-		}
-		a = b
-		b = temp
+	qInv := modInverse(q, p)
+	if qInv == nil {
+		return nil, errors.New("rsa: p and q must be co-prime")
 	}
+
+	return &PrivateKey{
+		PublicKey: PublicKey{N: n, E: e},
+		d:         d,
+		phi:       phi,
+		p:         new(big.Int).Set(p),
+		q:         new(big.Int).Set(q),
+		dP:        new(big.Int).Mod(d, new(big.Int).Sub(p, bigOne)),
+		dQ:        new(big.Int).Mod(d, new(big.Int).Sub(q, bigOne)),
+		qInv:      qInv,
+	}, nil
 }
 
-func modInverse(a int64, m int64) int64 {
-	var x int64
-	for x = 1; x < m; x++ {
-		if (a%m)*(x%m)%m == 1 {
-			return x
-		}
+// D returns a copy of the private exponent, which is e^-1 mod phi(N) for the keys built by NewPrivateKey
+func (pk *PrivateKey) D() *big.Int {
+	return new(big.Int).Set(pk.d)
+}
+
+// modInverse returns x such that a*x = 1 mod m, or nil if a is not invertible.
+// It runs the extended Euclidean algorithm, keeping old_s*a = old_r mod m at every step.
+func modInverse(a *big.Int, m *big.Int) *big.Int {
+	oldR, r := new(big.Int).Mod(a, m), new(big.Int).Set(m)
+	oldS, s := big.NewInt(1), big.NewInt(0)
+	quotient, tmp := new(big.Int), new(big.Int)
+
+	for r.Sign() != 0 {
+		quotient.Div(oldR, r)
+
+		tmp.Mul(quotient, r)
+		oldR, r = r, tmp.Sub(oldR, tmp)
+		tmp = new(big.Int)
+
+		tmp.Mul(quotient, s)
+		oldS, s = s, tmp.Sub(oldS, tmp)
+		tmp = new(big.Int)
 	}
-	return 0
+
+	// oldR is now gcd(a, m)
+	if oldR.Cmp(bigOne) != 0 {
+		return nil
+	}
+	return oldS.Mod(oldS, m)
 }
 
 type RSA struct {
@@ -81,23 +142,30 @@ type RSA struct {
 	PublicKey
 }
 
-func (r RSA) Encrypt(src []byte) ([]int64, error) {
-	var encNums []int64
+// Encrypt exponentiates every byte of src on its own, without padding
+func (r RSA) Encrypt(src []byte) ([]*big.Int, error) {
+	var encNums []*big.Int
+	e := big.NewInt(int64(r.E))
 	for _, b := range src {
 		encMessage := new(big.Int).SetBytes([]byte{b})
-		encMessage = encMessage.Exp(encMessage, new(big.Int).SetInt64(r.E), new(big.Int).SetInt64(r.N))
-		encNums = append(encNums, encMessage.Int64())
+		encMessage = encMessage.Exp(encMessage, e, r.N)
+		encNums = append(encNums, encMessage)
 	}
 
 	return encNums, nil
 }
-func (r RSA) Decrypt(src []int64) ([]byte, error) {
+func (r RSA) Decrypt(src []*big.Int) ([]byte, error) {
 
 	var decBytes []byte
 	for _, b := range src {
-		decMessage := new(big.Int).SetInt64(b)
-		decMessage = decMessage.Exp(decMessage, new(big.Int).SetInt64(r.d), new(big.Int).SetInt64(r.N))
-		decBytes = append(decBytes, decMessage.Bytes()[0])
+		decMessage, err := decrypt(&r.PrivateKey, b)
+		if err != nil {
+			return nil, err
+		}
+		if !decMessage.IsUint64() || decMessage.Uint64() > 255 {
+			return nil, errors.New("rsa: decryption error")
+		}
+		decBytes = append(decBytes, byte(decMessage.Uint64()))
 	}
 	return decBytes, nil
 }
@@ -105,10 +173,16 @@ func (r RSA) Name() string {
 	return "RSA"
 }
 
-// NewRSA creates and returns a RSA cipher.
+// NewRSA creates and returns a RSA cipher with a new 2048 bits key.
 func NewRSA() (RSA, error) {
-	var cipher RSA
-	cipher.setPublicKey()
-	cipher.setPrKey(cipher.PublicKey)
-	return cipher, nil
+	key, err := GenerateKey(rand.Reader, DefaultKeySize)
+	if err != nil {
+		return RSA{}, err
+	}
+	return NewRSAFromKey(key), nil
+}
+
+// NewRSAFromKey returns a RSA cipher using an existing key.
+func NewRSAFromKey(key *PrivateKey) RSA {
+	return RSA{PrivateKey: *key, PublicKey: key.PublicKey}
 }
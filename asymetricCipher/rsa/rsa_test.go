@@ -0,0 +1,100 @@
+package rsa
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+)
+
+// decryptPlain computes c^d mod N with one exponentiation modulo N, as before the CRT
+func decryptPlain(priv *PrivateKey, c *big.Int) *big.Int {
+	return new(big.Int).Exp(c, priv.d, priv.N)
+}
+
+func TestDecryptCRTMatchesPlain(t *testing.T) {
+	key := testKey(t)
+	for i := 0; i < 10; i++ {
+		c, err := rand.Int(rand.Reader, key.N)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := decrypt(key, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := decryptPlain(key, c); m.Cmp(want) != 0 {
+			t.Fatalf("CRT decryption of %x = %x, want %x", c, m, want)
+		}
+	}
+}
+
+// TestFaultCheck corrupts dP, as a hardware fault would, and expects no result: a faulty CRT result
+// is correct modulo q only, and its gcd with N would reveal q.
+func TestFaultCheck(t *testing.T) {
+	faulty := *testKey(t)
+	faulty.dP = new(big.Int).Add(faulty.dP, bigOne)
+
+	c := encrypt(&faulty.PublicKey, big.NewInt(42))
+	if _, err := decrypt(&faulty, c); err != ErrDecryption {
+		t.Fatalf("decrypt with a corrupted dP = %v, want ErrDecryption", err)
+	}
+
+	digest := sha256.Sum256([]byte("message"))
+	if signature, err := SignPKCS1v15(&faulty, crypto.SHA256, digest[:]); err == nil {
+		t.Fatalf("SignPKCS1v15 with a corrupted dP returned %x", signature)
+	}
+}
+
+func benchmarkCiphertext(b *testing.B) (*PrivateKey, *big.Int) {
+	key := testKey(b)
+	c, err := rand.Int(rand.Reader, key.N)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	return key, c
+}
+
+func BenchmarkDecryptCRT(b *testing.B) {
+	key, c := benchmarkCiphertext(b)
+	for i := 0; i < b.N; i++ {
+		if _, err := decrypt(key, c); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecryptPlain(b *testing.B) {
+	key, c := benchmarkCiphertext(b)
+	for i := 0; i < b.N; i++ {
+		decryptPlain(key, c)
+	}
+}
+
+func BenchmarkSignCRT(b *testing.B) {
+	key := testKey(b)
+	digest := sha256.Sum256([]byte("message"))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := SignPKCS1v15(key, crypto.SHA256, digest[:]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSignPlain signs the same encoded message as SignPKCS1v15, with one exponentiation modulo N
+func BenchmarkSignPlain(b *testing.B) {
+	key := testKey(b)
+	digest := sha256.Sum256([]byte("message"))
+	signature, err := SignPKCS1v15(key, crypto.SHA256, digest[:])
+	if err != nil {
+		b.Fatal(err)
+	}
+	em := encrypt(&key.PublicKey, new(big.Int).SetBytes(signature))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		decryptPlain(key, em).FillBytes(make([]byte, key.Size()))
+	}
+}
@@ -0,0 +1,283 @@
+package rsa
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// ErrVerification is returned for every signature that does not verify, whatever the reason
+var ErrVerification = errors.New("rsa: verification error")
+
+// hashPrefixes are the DER encodings of the DigestInfo header of every supported hash, as in RFC 8017
+var hashPrefixes = map[crypto.Hash][]byte{
+	crypto.SHA1:   {0x30, 0x21, 0x30, 0x09, 0x06, 0x05, 0x2b, 0x0e, 0x03, 0x02, 0x1a, 0x05, 0x00, 0x04, 0x14},
+	crypto.SHA224: {0x30, 0x2d, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x04, 0x05, 0x00, 0x04, 0x1c},
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	crypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+	crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+}
+
+// pkcs1v15HashInfo returns the DigestInfo prefix of hash, an empty one if hash is 0 and the digest is signed as is
+func pkcs1v15HashInfo(hash crypto.Hash, digestLen int) ([]byte, error) {
+	if hash == 0 {
+		return nil, nil
+	}
+	if hash.Size() != digestLen {
+		return nil, errors.New("rsa: input must be hashed message")
+	}
+	prefix, ok := hashPrefixes[hash]
+	if !ok {
+		return nil, errors.New("rsa: unsupported hash function")
+	}
+	return prefix, nil
+}
+
+// SignPKCS1v15 signs digest, the result of hashing a message with hash, using RSASSA-PKCS1-v1_5
+func SignPKCS1v15(priv *PrivateKey, hash crypto.Hash, digest []byte) ([]byte, error) {
+	prefix, err := pkcs1v15HashInfo(hash, len(digest))
+	if err != nil {
+		return nil, err
+	}
+
+	// EM = 0x00 || 0x01 || PS || 0x00 || T, PS being 0xff bytes and T the DigestInfo
+	tLen := len(prefix) + len(digest)
+	k := priv.Size()
+	if k < tLen+11 {
+		return nil, ErrMessageTooLong
+	}
+	em := make([]byte, k)
+	em[1] = 1
+	for i := 2; i < k-tLen-1; i++ {
+		em[i] = 0xff
+	}
+	copy(em[k-tLen:], prefix)
+	copy(em[k-len(digest):], digest)
+
+	s, err := decrypt(priv, new(big.Int).SetBytes(em))
+	if err != nil {
+		return nil, err
+	}
+	return s.FillBytes(make([]byte, k)), nil
+}
+
+// VerifyPKCS1v15 checks a RSASSA-PKCS1-v1_5 signature of digest. It returns nil if the signature is valid.
+func VerifyPKCS1v15(pub *PublicKey, hash crypto.Hash, digest []byte, signature []byte) error {
+	prefix, err := pkcs1v15HashInfo(hash, len(digest))
+	if err != nil {
+		return err
+	}
+
+	tLen := len(prefix) + len(digest)
+	k := pub.Size()
+	if k < tLen+11 || len(signature) != k {
+		return ErrVerification
+	}
+
+	s := new(big.Int).SetBytes(signature)
+	if s.Cmp(pub.N) >= 0 {
+		return ErrVerification
+	}
+	em := encrypt(pub, s).FillBytes(make([]byte, k))
+
+	ok := subtle.ConstantTimeByteEq(em[0], 0)
+	ok &= subtle.ConstantTimeByteEq(em[1], 1)
+	ok &= subtle.ConstantTimeCompare(em[k-len(digest):], digest)
+	ok &= subtle.ConstantTimeCompare(em[k-tLen:k-len(digest)], prefix)
+	ok &= subtle.ConstantTimeByteEq(em[k-tLen-1], 0)
+	for i := 2; i < k-tLen-1; i++ {
+		ok &= subtle.ConstantTimeByteEq(em[i], 0xff)
+	}
+
+	if ok != 1 {
+		return ErrVerification
+	}
+	return nil
+}
+
+const (
+	// PSSSaltLengthAuto uses the longest possible salt when signing, and detects the salt length when verifying
+	PSSSaltLengthAuto = 0
+	// PSSSaltLengthEqualsHash uses a salt as long as the digest
+	PSSSaltLengthEqualsHash = -1
+)
+
+// PSSOptions configure RSASSA-PSS signatures. A nil *PSSOptions signs with PSSSaltLengthEqualsHash
+// and verifies with PSSSaltLengthAuto.
+type PSSOptions struct {
+	SaltLength int
+}
+
+// SignPSS signs digest, the result of hashing a message with hash, using RSASSA-PSS with MGF1 over the same hash
+func SignPSS(random io.Reader, priv *PrivateKey, hash crypto.Hash, digest []byte, opts *PSSOptions) ([]byte, error) {
+	if hash.Size() != len(digest) {
+		return nil, errors.New("rsa: input must be hashed message")
+	}
+
+	emBits := priv.N.BitLen() - 1
+	emLen := (emBits + 7) / 8
+	saltLength := PSSSaltLengthEqualsHash
+	if opts != nil {
+		saltLength = opts.SaltLength
+	}
+	switch saltLength {
+	case PSSSaltLengthAuto:
+		saltLength = emLen - hash.Size() - 2
+	case PSSSaltLengthEqualsHash:
+		saltLength = hash.Size()
+	}
+	if saltLength < 0 {
+		return nil, ErrMessageTooLong
+	}
+
+	salt := make([]byte, saltLength)
+	if _, err := io.ReadFull(random, salt); err != nil {
+		return nil, err
+	}
+
+	em, err := emsaPSSEncode(digest, emBits, salt, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := decrypt(priv, new(big.Int).SetBytes(em))
+	if err != nil {
+		return nil, err
+	}
+	return s.FillBytes(make([]byte, priv.Size())), nil
+}
+
+// VerifyPSS checks a RSASSA-PSS signature of digest. It returns nil if the signature is valid.
+func VerifyPSS(pub *PublicKey, hash crypto.Hash, digest []byte, signature []byte, opts *PSSOptions) error {
+	if hash.Size() != len(digest) || len(signature) != pub.Size() {
+		return ErrVerification
+	}
+	saltLength := PSSSaltLengthAuto
+	if opts != nil {
+		saltLength = opts.SaltLength
+	}
+	if saltLength == PSSSaltLengthEqualsHash {
+		saltLength = hash.Size()
+	}
+
+	s := new(big.Int).SetBytes(signature)
+	if s.Cmp(pub.N) >= 0 {
+		return ErrVerification
+	}
+
+	emBits := pub.N.BitLen() - 1
+	emLen := (emBits + 7) / 8
+	em := encrypt(pub, s).FillBytes(make([]byte, pub.Size()))
+	// When the modulus has 8k+1 bits, the encoded message is one byte shorter than the modulus
+	if len(em) > emLen {
+		if em[0] != 0 {
+			return ErrVerification
+		}
+		em = em[1:]
+	}
+
+	return emsaPSSVerify(digest, em, emBits, saltLength, hash)
+}
+
+// emsaPSSEncode builds EM = maskedDB || H || 0xbc, as defined in RFC 8017 section 9.1.1
+func emsaPSSEncode(mHash []byte, emBits int, salt []byte, hash crypto.Hash) ([]byte, error) {
+	hLen := hash.Size()
+	sLen := len(salt)
+	emLen := (emBits + 7) / 8
+	if emLen < hLen+sLen+2 {
+		return nil, ErrMessageTooLong
+	}
+
+	// H = Hash(0x00 x 8 || mHash || salt)
+	h := hash.New()
+	h.Write(make([]byte, 8))
+	h.Write(mHash)
+	h.Write(salt)
+	digest := h.Sum(nil)
+	h.Reset()
+
+	// DB = PS || 0x01 || salt
+	em := make([]byte, emLen)
+	db := em[:emLen-hLen-1]
+	db[emLen-sLen-hLen-2] = 1
+	copy(db[emLen-sLen-hLen-1:], salt)
+
+	mgf1XOR(db, h, digest)
+	db[0] &= 0xff >> uint(8*emLen-emBits)
+
+	copy(em[emLen-hLen-1:], digest)
+	em[emLen-1] = 0xbc
+	return em, nil
+}
+
+// emsaPSSVerify checks EM against mHash, as defined in RFC 8017 section 9.1.2
+func emsaPSSVerify(mHash, em []byte, emBits, sLen int, hash crypto.Hash) error {
+	hLen := hash.Size()
+	emLen := (emBits + 7) / 8
+	if len(em) != emLen || emLen < hLen+sLen+2 || em[emLen-1] != 0xbc {
+		return ErrVerification
+	}
+
+	db := append([]byte(nil), em[:emLen-hLen-1]...)
+	digest := em[emLen-hLen-1 : emLen-1]
+
+	topBits := byte(0xff << uint(8-(8*emLen-emBits)))
+	if 8*emLen-emBits > 0 && db[0]&topBits != 0 {
+		return ErrVerification
+	}
+
+	h := hash.New()
+	mgf1XOR(db, h, digest)
+	db[0] &= 0xff >> uint(8*emLen-emBits)
+
+	// Find the 0x01 separator, everything before must be zero
+	separator := -1
+	for i, b := range db {
+		if b == 1 {
+			separator = i
+			break
+		}
+		if b != 0 {
+			return ErrVerification
+		}
+	}
+	if separator < 0 {
+		return ErrVerification
+	}
+	if sLen != PSSSaltLengthAuto && separator != emLen-hLen-sLen-2 {
+		return ErrVerification
+	}
+	salt := db[separator+1:]
+
+	h.Write(make([]byte, 8))
+	h.Write(mHash)
+	h.Write(salt)
+	if !bytes.Equal(h.Sum(nil), digest) {
+		return ErrVerification
+	}
+	return nil
+}
+
+// Sign signs digest, the result of hashing a message with hash, using RSASSA-PKCS1-v1_5
+func (r RSA) Sign(hash crypto.Hash, digest []byte) ([]byte, error) {
+	return SignPKCS1v15(&r.PrivateKey, hash, digest)
+}
+
+// Verify checks a RSASSA-PKCS1-v1_5 signature made by Sign
+func (r RSA) Verify(hash crypto.Hash, digest []byte, signature []byte) error {
+	return VerifyPKCS1v15(&r.PublicKey, hash, digest, signature)
+}
+
+// SignPSS signs digest using RSASSA-PSS, with a salt as long as the digest
+func (r RSA) SignPSS(hash crypto.Hash, digest []byte) ([]byte, error) {
+	return SignPSS(rand.Reader, &r.PrivateKey, hash, digest, nil)
+}
+
+// VerifyPSS checks a RSASSA-PSS signature, whatever its salt length
+func (r RSA) VerifyPSS(hash crypto.Hash, digest []byte, signature []byte) error {
+	return VerifyPSS(&r.PublicKey, hash, digest, signature, nil)
+}
@@ -0,0 +1,86 @@
+package rsa
+
+import (
+	"crypto"
+	"crypto/rand"
+	stdrsa "crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"testing"
+)
+
+func TestPKCS1v15SignatureInteroperability(t *testing.T) {
+	key := testKey(t)
+	std := key.toStdlib()
+
+	for _, hash := range []crypto.Hash{crypto.SHA256, crypto.SHA512} {
+		h := hash.New()
+		h.Write([]byte("a signed message"))
+		digest := h.Sum(nil)
+
+		signature, err := SignPKCS1v15(key, hash, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := stdrsa.VerifyPKCS1v15(&std.PublicKey, hash, digest, signature); err != nil {
+			t.Fatalf("crypto/rsa rejected the signature: %v", err)
+		}
+
+		signature, err = stdrsa.SignPKCS1v15(nil, std, hash, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifyPKCS1v15(&key.PublicKey, hash, digest, signature); err != nil {
+			t.Fatalf("rejected the crypto/rsa signature: %v", err)
+		}
+
+		signature[0] ^= 1
+		if err := VerifyPKCS1v15(&key.PublicKey, hash, digest, signature); err != ErrVerification {
+			t.Fatalf("VerifyPKCS1v15 of a modified signature = %v", err)
+		}
+	}
+}
+
+func TestPSSSignatureInteroperability(t *testing.T) {
+	key := testKey(t)
+	std := key.toStdlib()
+	digest256 := sha256.Sum256([]byte("a signed message"))
+	digest512 := sha512.Sum512([]byte("a signed message"))
+
+	tests := []struct {
+		hash       crypto.Hash
+		digest     []byte
+		saltLength int
+	}{
+		{crypto.SHA256, digest256[:], PSSSaltLengthEqualsHash},
+		{crypto.SHA256, digest256[:], PSSSaltLengthAuto},
+		{crypto.SHA512, digest512[:], 20},
+	}
+
+	for _, test := range tests {
+		// both packages use the same constants for the automatic and hash-sized salts
+		stdOpts := &stdrsa.PSSOptions{SaltLength: test.saltLength, Hash: test.hash}
+
+		signature, err := SignPSS(rand.Reader, key, test.hash, test.digest, &PSSOptions{SaltLength: test.saltLength})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := stdrsa.VerifyPSS(&std.PublicKey, test.hash, test.digest, signature, stdOpts); err != nil {
+			t.Fatalf("crypto/rsa rejected the signature with salt length %d: %v", test.saltLength, err)
+		}
+
+		signature, err = stdrsa.SignPSS(rand.Reader, std, test.hash, test.digest, stdOpts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifyPSS(&key.PublicKey, test.hash, test.digest, signature, nil); err != nil {
+			t.Fatalf("rejected the crypto/rsa signature with salt length %d: %v", test.saltLength, err)
+		}
+
+		test.digest[0] ^= 1
+		if err := VerifyPSS(&key.PublicKey, test.hash, test.digest, signature, nil); err != ErrVerification {
+			t.Fatalf("VerifyPSS of another digest = %v", err)
+		}
+		test.digest[0] ^= 1
+	}
+}
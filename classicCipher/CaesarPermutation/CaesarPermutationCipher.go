@@ -13,6 +13,11 @@ type CaesarPermutationCipher struct {
 func (c *CaesarPermutationCipher) SetKey(a int) {
 	c.key = a
 }
+
+// SetAlphabet replaces the shuffled alphabet, it must be a permutation of the 26 uppercase letters
+func (c *CaesarPermutationCipher) SetAlphabet(alphabet []rune) {
+	c.alphabet = alphabet
+}
 func (c CaesarPermutationCipher) Encrypt(text string) string {
 	s := rune(c.key)
 	runesText := []rune(text)
@@ -36,12 +41,12 @@ func (c CaesarPermutationCipher) Decrypt(text string) string {
 	for i, char := range runesText {
 		if char >= 'A' && char <= 'Z' {
 			idx := slices.IndexFunc(c.alphabet, func(c rune) bool { return c == char })
-			letter := rune(idx) + s
+			letter := rune(idx) - s
 			ind := (letter%26 + 26) % 26
 			runesText[i] = ind + 'A'
 		} else if char >= 'a' && char <= 'z' {
 			idx := slices.IndexFunc(c.alphabet, func(c rune) bool { return c == (char - 32) })
-			letter := rune(idx) + s
+			letter := rune(idx) - s
 			ind := (letter%26 + 26) % 26
 			runesText[i] = ind + 'a'
 		}
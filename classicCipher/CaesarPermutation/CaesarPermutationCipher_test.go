@@ -0,0 +1,36 @@
+package CaesarPermutation_test
+
+import (
+	"github.com/EliriaT/CS-Labs/classicCipher/CaesarPermutation"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	plaintext := "The Quick Brown Fox Jumps Over The Lazy Dog, 42 times!"
+	for _, key := range []int{0, 1, 3, 13, 25, 26, 40, -5} {
+		c := CaesarPermutation.MakeCaesarPermutationCipher()
+		c.SetKey(key)
+
+		ciphertext := c.Encrypt(plaintext)
+		if key%26 != 0 && ciphertext == plaintext {
+			t.Fatalf("key %d: ciphertext equals the plaintext", key)
+		}
+		if decrypted := c.Decrypt(ciphertext); decrypted != plaintext {
+			t.Fatalf("key %d: Decrypt(%q) = %q", key, ciphertext, decrypted)
+		}
+	}
+}
+
+func TestKnownCiphertext(t *testing.T) {
+	c := CaesarPermutation.MakeCaesarPermutationCipher()
+	c.SetAlphabet([]rune("ZYXWVUTSRQPONMLKJIHGFEDCBA"))
+	c.SetKey(3)
+
+	// A is shifted to D, the fourth letter of the alphabet, W
+	if got := c.Encrypt("Abc"); got != "Wvu" {
+		t.Fatalf("Encrypt = %q, want %q", got, "Wvu")
+	}
+	if got := c.Decrypt("Wvu"); got != "Abc" {
+		t.Fatalf("Decrypt = %q, want %q", got, "Abc")
+	}
+}
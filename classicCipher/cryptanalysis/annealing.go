@@ -0,0 +1,98 @@
+package cryptanalysis
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Progress is reported periodically by the hill-climbing solvers
+type Progress struct {
+	Iteration   int
+	Iterations  int
+	Temperature float64
+	Score       float64
+	BestScore   float64
+	BestKey     string
+}
+
+// SolverOptions configure the simulated annealing solvers.
+// Iterations is the number of candidate keys tried, 0 means the solver's default. Seed makes runs reproducible.
+// OnProgress, if not nil, is called every ProgressInterval iterations (by default every 1% of the run) and once at the end.
+type SolverOptions struct {
+	Iterations       int
+	Seed             int64
+	ProgressInterval int
+	OnProgress       func(Progress)
+}
+
+func (o SolverOptions) withDefaults(defaultIterations int) SolverOptions {
+	if o.Iterations <= 0 {
+		o.Iterations = defaultIterations
+	}
+	if o.ProgressInterval <= 0 {
+		o.ProgressInterval = o.Iterations / 100
+		if o.ProgressInterval == 0 {
+			o.ProgressInterval = 1
+		}
+	}
+	return o
+}
+
+// startTemperature scales the initial temperature with the text length, since quadgram scores are sums over the text
+func startTemperature(textLength int) float64 {
+	return math.Max(10+0.087*float64(textLength-84), 1)
+}
+
+// anneal runs simulated annealing on key, mutated in place by mutate and rated by score (the higher the better).
+// The iterations are split in cycles; in every cycle the temperature decreases linearly from temperature to 0,
+// so each cycle ends as a pure hill climb, and the next cycle reheats starting from the best key seen so far.
+// It returns the best key seen together with its score.
+func anneal(key []byte, temperature float64, cycles int, options SolverOptions, mutate func(key []byte, rnd *rand.Rand), score func(key []byte) float64) ([]byte, float64) {
+	rnd := rand.New(rand.NewSource(options.Seed))
+
+	parent := append([]byte(nil), key...)
+	parentScore := score(parent)
+	best := append([]byte(nil), parent...)
+	bestScore := parentScore
+	child := make([]byte, len(key))
+
+	cycleLength := options.Iterations / cycles
+	if cycleLength == 0 {
+		cycleLength = options.Iterations
+	}
+
+	for i := 1; i <= options.Iterations; i++ {
+		step := (i - 1) % cycleLength
+		if step == 0 && i > 1 {
+			copy(parent, best)
+			parentScore = bestScore
+		}
+		t := temperature * (1 - float64(step+1)/float64(cycleLength))
+
+		copy(child, parent)
+		mutate(child, rnd)
+		childScore := score(child)
+
+		delta := childScore - parentScore
+		if delta >= 0 || (t > 0 && rnd.Float64() < math.Exp(delta/t)) {
+			parent, child = child, parent
+			parentScore = childScore
+			if parentScore > bestScore {
+				copy(best, parent)
+				bestScore = parentScore
+			}
+		}
+
+		if options.OnProgress != nil && (i%options.ProgressInterval == 0 || i == options.Iterations) {
+			options.OnProgress(Progress{
+				Iteration:   i,
+				Iterations:  options.Iterations,
+				Temperature: t,
+				Score:       parentScore,
+				BestScore:   bestScore,
+				BestKey:     string(best),
+			})
+		}
+	}
+	return best, bestScore
+}
@@ -0,0 +1,69 @@
+package cryptanalysis
+
+import (
+	"errors"
+	"github.com/EliriaT/CS-Labs/classicCipher/Caesar"
+	"math"
+	"sort"
+)
+
+var ErrNoLetters = errors.New("ciphertext contains no letters")
+
+// CaesarCandidate is a possible key of a Caesar ciphertext together with its chi-squared score
+type CaesarCandidate struct {
+	Key       int     `json:"key"`
+	Score     float64 `json:"score"`
+	Plaintext string  `json:"plaintext"`
+}
+
+// CaesarResult is the outcome of a ciphertext-only attack on the Caesar cipher.
+// Candidates holds all 26 keys, ordered from the most to the least probable one.
+type CaesarResult struct {
+	Key        int               `json:"key"`
+	Confidence float64           `json:"confidence"`
+	Plaintext  string            `json:"plaintext"`
+	Candidates []CaesarCandidate `json:"candidates"`
+}
+
+// BreakCaesar recovers the key of a text encrypted with Caesar.CaesarCipher, using only the ciphertext.
+// Every key is ranked by the chi-squared distance between the decrypted text and English letter frequencies.
+// The confidence is the share of the best key in the likelihoods of all keys, from 0 to 1.
+func BreakCaesar(ciphertext string) (CaesarResult, error) {
+	counts, total := letterCounts(ciphertext)
+	if total == 0 {
+		return CaesarResult{}, ErrNoLetters
+	}
+
+	cipher := Caesar.MakeCaesarCipher()
+	candidates := make([]CaesarCandidate, 0, 26)
+	for key := 0; key < 26; key++ {
+		cipher.SetKey(key)
+		candidates = append(candidates, CaesarCandidate{
+			Key:       key,
+			Score:     chiSquared(counts, total, key),
+			Plaintext: cipher.Decrypt(ciphertext),
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score < candidates[j].Score
+	})
+
+	return CaesarResult{
+		Key:        candidates[0].Key,
+		Confidence: confidence(candidates),
+		Plaintext:  candidates[0].Plaintext,
+		Candidates: candidates,
+	}, nil
+}
+
+// confidence treats exp(-score/2) as the likelihood of each candidate and returns the normalised likelihood of the first one.
+// The candidates must be sorted by score.
+func confidence(candidates []CaesarCandidate) float64 {
+	best := candidates[0].Score
+	sum := 0.0
+	for _, candidate := range candidates {
+		sum += math.Exp(-(candidate.Score - best) / 2)
+	}
+	return 1 / sum
+}
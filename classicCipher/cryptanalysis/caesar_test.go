@@ -0,0 +1,83 @@
+package cryptanalysis_test
+
+import (
+	"github.com/EliriaT/CS-Labs/classicCipher/Caesar"
+	"github.com/EliriaT/CS-Labs/classicCipher/cryptanalysis"
+	"math"
+	"testing"
+)
+
+// english is a few hundred letters of ordinary English prose, the ciphertext of the attacks is made from it
+const english = "It was the best of times, it was the worst of times, it was the age of wisdom, it was the age of " +
+	"foolishness, it was the epoch of belief, it was the epoch of incredulity, it was the season of Light, it was " +
+	"the season of Darkness, it was the spring of hope, it was the winter of despair, we had everything before us, " +
+	"we had nothing before us, we were all going direct to Heaven, we were all going direct the other way. In short, " +
+	"the period was so far like the present period, that some of its noisiest authorities insisted on its being " +
+	"received, for good or for evil, in the superlative degree of comparison only."
+
+func TestBreakCaesar(t *testing.T) {
+	tests := []struct {
+		name      string
+		plaintext string
+		key       int
+	}{
+		{"no shift", english, 0},
+		{"shift 3", english, 3},
+		{"shift 13", english, 13},
+		{"shift 25", english, 25},
+		{"one sentence", "The quick brown fox jumps over the lazy dog while the farmer sleeps in the barn", 7},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cipher := Caesar.MakeCaesarCipher()
+			cipher.SetKey(test.key)
+			ciphertext := cipher.Encrypt(test.plaintext)
+
+			result, err := cryptanalysis.BreakCaesar(ciphertext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Key != test.key {
+				t.Fatalf("key = %d, want %d", result.Key, test.key)
+			}
+			if result.Plaintext != test.plaintext {
+				t.Fatalf("plaintext = %q, want %q", result.Plaintext, test.plaintext)
+			}
+			if len(result.Candidates) != 26 || result.Candidates[0].Key != test.key {
+				t.Fatalf("the candidates are not ranked with the key first: %+v", result.Candidates)
+			}
+			for i := 1; i < len(result.Candidates); i++ {
+				if result.Candidates[i].Score < result.Candidates[i-1].Score {
+					t.Fatalf("candidate %d scores better than candidate %d", i, i-1)
+				}
+			}
+			if result.Confidence <= 0.5 || result.Confidence > 1 {
+				t.Fatalf("confidence = %v, want the key to dominate", result.Confidence)
+			}
+		})
+	}
+}
+
+func TestBreakCaesarNoLetters(t *testing.T) {
+	for _, ciphertext := range []string{"", "1234 !?", "\n\t"} {
+		if _, err := cryptanalysis.BreakCaesar(ciphertext); err != cryptanalysis.ErrNoLetters {
+			t.Errorf("BreakCaesar(%q) = %v, want ErrNoLetters", ciphertext, err)
+		}
+	}
+}
+
+func TestChiSquared(t *testing.T) {
+	cipher := Caesar.MakeCaesarCipher()
+	cipher.SetKey(10)
+	if plain, shifted := cryptanalysis.ChiSquared(english), cryptanalysis.ChiSquared(cipher.Encrypt(english)); plain >= shifted {
+		t.Fatalf("English scores %v, no closer to English than its Caesar ciphertext at %v", plain, shifted)
+	}
+	// the score ignores case and everything but the letters
+	if a, b := cryptanalysis.ChiSquared("Hello, World!"), cryptanalysis.ChiSquared("helloworld"); a != b {
+		t.Fatalf("ChiSquared depends on case or punctuation: %v != %v", a, b)
+	}
+	if score := cryptanalysis.ChiSquared("42"); !math.IsInf(score, 1) {
+		t.Fatalf("ChiSquared of a text without letters = %v, want +Inf", score)
+	}
+}
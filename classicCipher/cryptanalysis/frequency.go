@@ -0,0 +1,49 @@
+package cryptanalysis
+
+import "math"
+
+// englishFrequencies holds the relative frequency of the letters A-Z in English text
+var englishFrequencies = [26]float64{
+	0.08167, 0.01492, 0.02782, 0.04253, 0.12702, 0.02228, 0.02015,
+	0.06094, 0.06966, 0.00153, 0.00772, 0.04025, 0.02406, 0.06749,
+	0.07507, 0.01929, 0.00095, 0.05987, 0.06327, 0.09056, 0.02758,
+	0.00978, 0.02360, 0.00150, 0.01974, 0.00074,
+}
+
+// letterCounts counts the occurrences of every latin letter in text, ignoring case.
+// It also returns the total number of letters found.
+func letterCounts(text string) ([26]int, int) {
+	var counts [26]int
+	total := 0
+	for _, char := range text {
+		if char >= 'A' && char <= 'Z' {
+			counts[char-'A']++
+			total++
+		} else if char >= 'a' && char <= 'z' {
+			counts[char-'a']++
+			total++
+		}
+	}
+	return counts, total
+}
+
+// chiSquared scores letter counts against English, as if every letter was shifted back by shift positions.
+// The lower the score, the closer the distribution is to English.
+func chiSquared(counts [26]int, total int, shift int) float64 {
+	score := 0.0
+	for i := 0; i < 26; i++ {
+		observed := float64(counts[((i+shift)%26+26)%26])
+		expected := englishFrequencies[i] * float64(total)
+		score += (observed - expected) * (observed - expected) / expected
+	}
+	return score
+}
+
+// ChiSquared returns the chi-squared statistic of the letter distribution of text against English
+func ChiSquared(text string) float64 {
+	counts, total := letterCounts(text)
+	if total == 0 {
+		return math.Inf(1)
+	}
+	return chiSquared(counts, total, 0)
+}
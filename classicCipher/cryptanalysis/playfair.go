@@ -0,0 +1,126 @@
+package cryptanalysis
+
+import (
+	"errors"
+	"github.com/EliriaT/CS-Labs/classicCipher/Playfair"
+	"github.com/EliriaT/CS-Labs/classicCipher/Vigener"
+	"math/rand"
+	"strings"
+)
+
+const (
+	defaultPlayfairIterations = 2000000
+	// playfairCycles reheats the search a few times, the table often settles on a key with a few letters misplaced
+	playfairCycles = 5
+)
+
+var ErrInvalidPlayfairText = errors.New("playfair ciphertext must have an even number of letters and no J")
+
+// PlayfairResult is the outcome of an attack on the Playfair cipher.
+// Key holds the 25 letters of the 5x5 table, row by row; Playfair.MakePlayfairCipher(Key) rebuilds the same table.
+type PlayfairResult struct {
+	Key       string  `json:"key"`
+	Score     float64 `json:"score"`
+	Plaintext string  `json:"plaintext"`
+}
+
+// Table returns the recovered 5x5 table, row by row
+func (r PlayfairResult) Table() [5]string {
+	var table [5]string
+	for row := 0; row < 5; row++ {
+		table[row] = r.Key[row*5 : row*5+5]
+	}
+	return table
+}
+
+// SolvePlayfair recovers the 5x5 table of a text encrypted with Playfair.PlayfairCipher, using simulated annealing
+// scored by English quadgram statistics. The table found may be a rotation of the original one, which encrypts the same way.
+// The table is reported in the BestKey of every Progress.
+func SolvePlayfair(ciphertext string, options SolverOptions) (PlayfairResult, error) {
+	text := Vigener.CleanString(ciphertext)
+	if len(text) == 0 {
+		return PlayfairResult{}, ErrNoLetters
+	}
+	if len(text)%2 != 0 || strings.ContainsRune(text, 'J') {
+		return PlayfairResult{}, ErrInvalidPlayfairText
+	}
+	options = options.withDefaults(defaultPlayfairIterations)
+
+	plaintext := make([]byte, len(text))
+	score := func(key []byte) float64 {
+		decryptPlayfair(key, text, plaintext)
+		return quadgramScore(plaintext)
+	}
+
+	key, bestScore := anneal([]byte("ABCDEFGHIKLMNOPQRSTUVWXYZ"), playfairTemperature(len(text)), playfairCycles, options, mutatePlayfairKey, score)
+
+	return PlayfairResult{
+		Key:       string(key),
+		Score:     bestScore,
+		Plaintext: Playfair.MakePlayfairCipher(string(key)).Decrypt(text),
+	}, nil
+}
+
+// playfairTemperature is the initial temperature of the Playfair search. A swap in the table changes the decryption
+// of every pair using either letter, so the scores move more than for a substitution and the search starts cooler.
+func playfairTemperature(textLength int) float64 {
+	return startTemperature(textLength) / 2
+}
+
+// decryptPlayfair decrypts text with the table given by key into plaintext, as letter values from 0 to 25
+func decryptPlayfair(key []byte, text string, plaintext []byte) {
+	var row, column [26]int
+	for i, letter := range key {
+		row[letter-'A'] = i / 5
+		column[letter-'A'] = i % 5
+	}
+
+	for i := 0; i+1 < len(text); i += 2 {
+		a, b := text[i]-'A', text[i+1]-'A'
+		r1, c1, r2, c2 := row[a], column[a], row[b], column[b]
+		if r1 == r2 {
+			c1, c2 = (c1+4)%5, (c2+4)%5
+		} else if c1 == c2 {
+			r1, r2 = (r1+4)%5, (r2+4)%5
+		} else {
+			c1, c2 = c2, c1
+		}
+		plaintext[i] = key[r1*5+c1] - 'A'
+		plaintext[i+1] = key[r2*5+c2] - 'A'
+	}
+}
+
+// mutatePlayfairKey mostly swaps two letters of the table, and sometimes swaps rows or columns or flips the whole table
+func mutatePlayfairKey(key []byte, rnd *rand.Rand) {
+	switch n := rnd.Intn(50); {
+	case n == 0:
+		a, b := rnd.Intn(5), rnd.Intn(5)
+		for c := 0; c < 5; c++ {
+			key[a*5+c], key[b*5+c] = key[b*5+c], key[a*5+c]
+		}
+	case n == 1:
+		a, b := rnd.Intn(5), rnd.Intn(5)
+		for r := 0; r < 5; r++ {
+			key[r*5+a], key[r*5+b] = key[r*5+b], key[r*5+a]
+		}
+	case n == 2:
+		for r := 0; r < 2; r++ {
+			for c := 0; c < 5; c++ {
+				key[r*5+c], key[(4-r)*5+c] = key[(4-r)*5+c], key[r*5+c]
+			}
+		}
+	case n == 3:
+		for r := 0; r < 5; r++ {
+			for c := 0; c < 2; c++ {
+				key[r*5+c], key[r*5+4-c] = key[r*5+4-c], key[r*5+c]
+			}
+		}
+	case n == 4:
+		for i := 0; i < 12; i++ {
+			key[i], key[24-i] = key[24-i], key[i]
+		}
+	default:
+		i, j := rnd.Intn(25), rnd.Intn(25)
+		key[i], key[j] = key[j], key[i]
+	}
+}
@@ -0,0 +1,45 @@
+package cryptanalysis_test
+
+import (
+	"github.com/EliriaT/CS-Labs/classicCipher/Playfair"
+	"github.com/EliriaT/CS-Labs/classicCipher/cryptanalysis"
+	"testing"
+)
+
+// expedition is English without the repetitions of english, which let a Playfair search settle on a wrong table
+const expedition = "When the expedition reached the river at last, the guides refused to cross before morning, because the " +
+	"current had risen during the night and carried whole trees past the camp. The captain argued with them for an " +
+	"hour, then ordered the men to build fires, check the powder and mend the boats. Nobody slept much. At dawn the " +
+	"water was lower, the fog lifted slowly from the valley, and the small company crossed in three trips without " +
+	"losing a single horse."
+
+func TestSolvePlayfair(t *testing.T) {
+	if testing.Short() {
+		t.Skip("the search takes a few seconds")
+	}
+	cipher := Playfair.MakePlayfairCipher("MONARCHY")
+	ciphertext := cipher.Encrypt(expedition)
+
+	result, err := cryptanalysis.SolvePlayfair(ciphertext, cryptanalysis.SolverOptions{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the table found may be a rotation of the key's, so it is checked by what it decrypts
+	if want := cipher.Decrypt(ciphertext); result.Plaintext != want {
+		t.Fatalf("key %s decrypts to %q, want %q", result.Key, result.Plaintext, want)
+	}
+	if got := Playfair.MakePlayfairCipher(result.Key).Encrypt(expedition); got != ciphertext {
+		t.Fatalf("key %s encrypts to %q, want %q", result.Key, got, ciphertext)
+	}
+}
+
+func TestSolvePlayfairInvalidText(t *testing.T) {
+	for _, ciphertext := range []string{"ABC", "JAMS"} {
+		if _, err := cryptanalysis.SolvePlayfair(ciphertext, cryptanalysis.SolverOptions{}); err != cryptanalysis.ErrInvalidPlayfairText {
+			t.Errorf("SolvePlayfair(%q) = %v, want ErrInvalidPlayfairText", ciphertext, err)
+		}
+	}
+	if _, err := cryptanalysis.SolvePlayfair("1234", cryptanalysis.SolverOptions{}); err != cryptanalysis.ErrNoLetters {
+		t.Errorf("SolvePlayfair of a text without letters = %v, want ErrNoLetters", err)
+	}
+}
@@ -0,0 +1,82 @@
+package cryptanalysis
+
+import (
+	_ "embed"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// quadgramData holds the counts of the four-letter sequences found in Newton's Opticks (Project Gutenberg),
+// one "QUAD count" pair per line. Quadgrams that occur only once are left out.
+//
+//go:embed quadgrams.txt
+var quadgramData string
+
+var (
+	quadgramOnce   sync.Once
+	quadgramScores []float64
+)
+
+// loadQuadgrams turns the embedded counts into log10 probabilities, indexed by the four letters in base 26.
+// Unseen quadgrams get a floor probability, much lower than the one of any seen quadgram.
+func loadQuadgrams() {
+	counts := map[int]float64{}
+	total := 0.0
+	for _, line := range strings.Split(quadgramData, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || len(fields[0]) != 4 {
+			continue
+		}
+		count, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			continue
+		}
+		index := 0
+		for _, char := range fields[0] {
+			index = index*26 + int(char-'A')
+		}
+		counts[index] = count
+		total += count
+	}
+
+	quadgramScores = make([]float64, 26*26*26*26)
+	floor := math.Log10(0.01 / total)
+	for i := range quadgramScores {
+		quadgramScores[i] = floor
+	}
+	for index, count := range counts {
+		quadgramScores[index] = math.Log10(count / total)
+	}
+}
+
+// quadgramScore sums the log10 probabilities of all quadgrams of letters, which are given as values from 0 to 25
+func quadgramScore(letters []byte) float64 {
+	quadgramOnce.Do(loadQuadgrams)
+	score := 0.0
+	for i := 0; i+4 <= len(letters); i++ {
+		index := ((int(letters[i])*26+int(letters[i+1]))*26+int(letters[i+2]))*26 + int(letters[i+3])
+		score += quadgramScores[index]
+	}
+	return score
+}
+
+// QuadgramScore rates how much text looks like English, using the statistics of four-letter sequences.
+// Non-letters are ignored. The higher (closer to 0) the score, the more English-like the text.
+func QuadgramScore(text string) float64 {
+	return quadgramScore(letterIndices(text))
+}
+
+// letterIndices keeps only the latin letters of text, as values from 0 to 25
+func letterIndices(text string) []byte {
+	letters := make([]byte, 0, len(text))
+	for _, char := range text {
+		if char >= 'A' && char <= 'Z' {
+			letters = append(letters, byte(char-'A'))
+		} else if char >= 'a' && char <= 'z' {
+			letters = append(letters, byte(char-'a'))
+		}
+	}
+	return letters
+}
@@ -0,0 +1,85 @@
+package cryptanalysis
+
+import (
+	"github.com/EliriaT/CS-Labs/classicCipher/CaesarPermutation"
+	"math/rand"
+	"sort"
+)
+
+const (
+	defaultSubstitutionIterations = 100000
+	// substitutionCycles reheats the search often, since a substitution gets stuck in local maxima more easily than a Playfair table
+	substitutionCycles = 10
+)
+
+// frequencyOrder lists the English letters from the most to the least frequent one
+const frequencyOrder = "ETAOINSHRDLCUMWFGYPBVKJXQZ"
+
+// SubstitutionResult is the outcome of an attack on a monoalphabetic substitution.
+// Alphabet[i] is the ciphertext letter standing for the plaintext letter 'A'+i.
+type SubstitutionResult struct {
+	Alphabet  string  `json:"alphabet"`
+	Score     float64 `json:"score"`
+	Plaintext string  `json:"plaintext"`
+}
+
+// SolveCaesarPermutation recovers the shuffled alphabet of a text encrypted with CaesarPermutation.CaesarPermutationCipher,
+// using simulated annealing scored by English quadgram statistics. A shift applied on top of the shuffled
+// alphabet can not be told apart from the permutation itself, so the returned Alphabet is the shuffled
+// alphabet rotated by the cipher's key; with the key 0 it is the shuffled alphabet.
+// The Alphabet is reported in the BestKey of every Progress.
+func SolveCaesarPermutation(ciphertext string, options SolverOptions) (SubstitutionResult, error) {
+	letters := letterIndices(ciphertext)
+	if len(letters) == 0 {
+		return SubstitutionResult{}, ErrNoLetters
+	}
+	options = options.withDefaults(defaultSubstitutionIterations)
+
+	plaintext := make([]byte, len(letters))
+	score := func(alphabet []byte) float64 {
+		var decode [26]byte
+		for plain, cipher := range alphabet {
+			decode[cipher-'A'] = byte(plain)
+		}
+		for i, letter := range letters {
+			plaintext[i] = decode[letter]
+		}
+		return quadgramScore(plaintext)
+	}
+
+	mutate := func(alphabet []byte, rnd *rand.Rand) {
+		i, j := rnd.Intn(26), rnd.Intn(26)
+		alphabet[i], alphabet[j] = alphabet[j], alphabet[i]
+	}
+
+	alphabet, bestScore := anneal(frequencyGuess(letters), startTemperature(len(letters)), substitutionCycles, options, mutate, score)
+
+	cipher := CaesarPermutation.MakeCaesarPermutationCipher()
+	cipher.SetAlphabet([]rune(string(alphabet)))
+	cipher.SetKey(0)
+
+	return SubstitutionResult{
+		Alphabet:  string(alphabet),
+		Score:     bestScore,
+		Plaintext: cipher.Decrypt(ciphertext),
+	}, nil
+}
+
+// frequencyGuess is the starting alphabet of the solver: the most frequent ciphertext letter stands for E, the next one for T and so on
+func frequencyGuess(letters []byte) []byte {
+	var counts [26]int
+	for _, letter := range letters {
+		counts[letter]++
+	}
+
+	cipherOrder := []byte("ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	sort.SliceStable(cipherOrder, func(i, j int) bool {
+		return counts[cipherOrder[i]-'A'] > counts[cipherOrder[j]-'A']
+	})
+
+	alphabet := make([]byte, 26)
+	for rank, plain := range []byte(frequencyOrder) {
+		alphabet[plain-'A'] = cipherOrder[rank]
+	}
+	return alphabet
+}
@@ -0,0 +1,200 @@
+package cryptanalysis
+
+import (
+	"github.com/EliriaT/CS-Labs/classicCipher/Vigener"
+	"sort"
+	"strings"
+)
+
+const (
+	// englishIC is the index of coincidence of English text, randomIC the one of uniformly random letters
+	englishIC = 0.0667
+	randomIC  = 0.0385
+
+	defaultMaxKeyLength = 20
+	// candidateLengths is how many of the most probable key lengths are solved
+	candidateLengths = 5
+	// kasiskiWeight is how much the Kasiski examination counts in the key length score, compared to the index of coincidence
+	kasiskiWeight = 0.5
+)
+
+// VigenereCandidate is a possible key of a Vigenere ciphertext.
+// Score rates the key length, the higher the better. ChiSquared compares the resulting plaintext to English, the lower the better.
+type VigenereCandidate struct {
+	Key        string  `json:"key"`
+	KeyLength  int     `json:"key_length"`
+	Score      float64 `json:"score"`
+	ChiSquared float64 `json:"chi_squared"`
+	Plaintext  string  `json:"plaintext"`
+}
+
+// VigenereResult is the outcome of a ciphertext-only attack on the Vigenere cipher.
+// Candidates are ordered from the most to the least probable key.
+type VigenereResult struct {
+	Key              string              `json:"key"`
+	Plaintext        string              `json:"plaintext"`
+	FriedmanEstimate float64             `json:"friedman_estimate"`
+	Candidates       []VigenereCandidate `json:"candidates"`
+}
+
+// BreakVigenere recovers the key of a text encrypted with Vigener.VigenereCipher, using only the ciphertext.
+// The key length is estimated with the Kasiski examination and the index of coincidence of the columns,
+// then every column is solved as a Caesar shift. Keys longer than maxKeyLength are not tried, a value
+// less than 1 means the default of 20 letters.
+func BreakVigenere(ciphertext string, maxKeyLength int) (VigenereResult, error) {
+	text := Vigener.CleanString(ciphertext)
+	if len(text) == 0 {
+		return VigenereResult{}, ErrNoLetters
+	}
+
+	if maxKeyLength < 1 {
+		maxKeyLength = defaultMaxKeyLength
+	}
+	if maxKeyLength > len(text)/2 {
+		maxKeyLength = len(text) / 2
+	}
+	if maxKeyLength < 1 {
+		maxKeyLength = 1
+	}
+
+	distances := kasiskiDistances(text)
+
+	type keyLength struct {
+		length int
+		score  float64
+	}
+	lengths := make([]keyLength, 0, maxKeyLength)
+	for length := 1; length <= maxKeyLength; length++ {
+		score := averageColumnIC(text, length)/englishIC + kasiskiWeight*kasiskiShare(distances, length)
+		lengths = append(lengths, keyLength{length: length, score: score})
+	}
+	sort.SliceStable(lengths, func(i, j int) bool {
+		return lengths[i].score > lengths[j].score
+	})
+	if len(lengths) > candidateLengths {
+		lengths = lengths[:candidateLengths]
+	}
+
+	candidates := make([]VigenereCandidate, 0, len(lengths))
+	seen := map[string]bool{}
+	for _, l := range lengths {
+		key := shortestPeriod(solveColumns(text, l.length))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		plaintext := Vigener.MakeVigenereCipher(key).Decrypt(text)
+		candidates = append(candidates, VigenereCandidate{
+			Key:        key,
+			KeyLength:  len(key),
+			Score:      l.score,
+			ChiSquared: ChiSquared(plaintext),
+			Plaintext:  plaintext,
+		})
+	}
+
+	return VigenereResult{
+		Key:              candidates[0].Key,
+		Plaintext:        candidates[0].Plaintext,
+		FriedmanEstimate: friedmanEstimate(text),
+		Candidates:       candidates,
+	}, nil
+}
+
+// kasiskiDistances returns the distances between consecutive occurrences of every repeated trigram
+func kasiskiDistances(text string) []int {
+	var distances []int
+	lastSeen := map[string]int{}
+	for i := 0; i+3 <= len(text); i++ {
+		trigram := text[i : i+3]
+		if last, ok := lastSeen[trigram]; ok {
+			distances = append(distances, i-last)
+		}
+		lastSeen[trigram] = i
+	}
+	return distances
+}
+
+// kasiskiShare is the fraction of the Kasiski distances that are a multiple of length
+func kasiskiShare(distances []int, length int) float64 {
+	if len(distances) == 0 {
+		return 0
+	}
+	divisible := 0
+	for _, distance := range distances {
+		if distance%length == 0 {
+			divisible++
+		}
+	}
+	return float64(divisible) / float64(len(distances))
+}
+
+// indexOfCoincidence is the probability that two letters picked at random from text are equal
+func indexOfCoincidence(counts [26]int, total int) float64 {
+	if total < 2 {
+		return 0
+	}
+	sum := 0
+	for _, count := range counts {
+		sum += count * (count - 1)
+	}
+	return float64(sum) / float64(total*(total-1))
+}
+
+// averageColumnIC splits text in length columns, each encrypted with the same key letter, and averages their indexes of coincidence
+func averageColumnIC(text string, length int) float64 {
+	sum := 0.0
+	for column := 0; column < length; column++ {
+		counts, total := letterCounts(columnOf(text, column, length))
+		sum += indexOfCoincidence(counts, total)
+	}
+	return sum / float64(length)
+}
+
+// friedmanEstimate is the key length predicted by the Friedman test from the index of coincidence of the whole text
+func friedmanEstimate(text string) float64 {
+	counts, total := letterCounts(text)
+	ic := indexOfCoincidence(counts, total)
+	n := float64(total)
+	denominator := (englishIC - ic) + n*(ic-randomIC)
+	if denominator <= 0 {
+		return 0
+	}
+	return (englishIC - randomIC) * n / denominator
+}
+
+// solveColumns finds, for every column, the Caesar shift that makes it look the most like English
+func solveColumns(text string, length int) string {
+	key := make([]byte, length)
+	for column := 0; column < length; column++ {
+		counts, total := letterCounts(columnOf(text, column, length))
+		bestShift, bestScore := 0, 0.0
+		for shift := 0; shift < 26; shift++ {
+			score := chiSquared(counts, total, shift)
+			if shift == 0 || score < bestScore {
+				bestShift, bestScore = shift, score
+			}
+		}
+		key[column] = byte('A' + bestShift)
+	}
+	return string(key)
+}
+
+func columnOf(text string, column, length int) string {
+	var builder strings.Builder
+	for i := column; i < len(text); i += length {
+		builder.WriteByte(text[i])
+	}
+	return builder.String()
+}
+
+// shortestPeriod reduces a key made of a repeated word, like LEMONLEMON, to the word itself
+func shortestPeriod(key string) string {
+	for period := 1; period < len(key); period++ {
+		if len(key)%period == 0 && strings.Repeat(key[:period], len(key)/period) == key {
+			return key[:period]
+		}
+	}
+	return key
+}
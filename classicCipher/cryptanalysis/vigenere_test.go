@@ -0,0 +1,71 @@
+package cryptanalysis_test
+
+import (
+	"github.com/EliriaT/CS-Labs/classicCipher/Vigener"
+	"github.com/EliriaT/CS-Labs/classicCipher/cryptanalysis"
+	"testing"
+)
+
+func TestBreakVigenere(t *testing.T) {
+	tests := []struct {
+		key          string
+		maxKeyLength int
+	}{
+		{"LEMONADE", 0},
+		{"KEY", 0},
+		{"CRYPTOGRAPHY", 15},
+		{"B", 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			ciphertext := Vigener.MakeVigenereCipher(test.key).Encrypt(english)
+
+			result, err := cryptanalysis.BreakVigenere(ciphertext, test.maxKeyLength)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Key != test.key {
+				t.Fatalf("key = %q, want %q", result.Key, test.key)
+			}
+			if want := Vigener.CleanString(english); result.Plaintext != want {
+				t.Fatalf("plaintext = %q, want %q", result.Plaintext, want)
+			}
+			if len(result.Candidates) == 0 || result.Candidates[0].Key != test.key {
+				t.Fatalf("the key is not the first candidate: %+v", result.Candidates)
+			}
+		})
+	}
+}
+
+// TestBreakVigenereMaxKeyLength checks that keys longer than the limit are not found
+func TestBreakVigenereMaxKeyLength(t *testing.T) {
+	ciphertext := Vigener.MakeVigenereCipher("LEMONADE").Encrypt(english)
+	result, err := cryptanalysis.BreakVigenere(ciphertext, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, candidate := range result.Candidates {
+		if candidate.KeyLength > 5 || len(candidate.Key) != candidate.KeyLength {
+			t.Fatalf("candidate %+v is longer than the limit", candidate)
+		}
+	}
+}
+
+func TestBreakVigenereShortInput(t *testing.T) {
+	for _, ciphertext := range []string{"", "1234 !?", "--"} {
+		if _, err := cryptanalysis.BreakVigenere(ciphertext, 0); err != cryptanalysis.ErrNoLetters {
+			t.Errorf("BreakVigenere(%q) = %v, want ErrNoLetters", ciphertext, err)
+		}
+	}
+	// too short to say anything, but the breaker must still answer with keys that fit the text
+	for _, ciphertext := range []string{"A", "QX", "hello"} {
+		result, err := cryptanalysis.BreakVigenere(ciphertext, 0)
+		if err != nil {
+			t.Fatalf("BreakVigenere(%q): %v", ciphertext, err)
+		}
+		if len(result.Key) == 0 || len(result.Key) > len(ciphertext) {
+			t.Fatalf("BreakVigenere(%q) found the key %q", ciphertext, result.Key)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package bcrypt
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/EliriaT/CS-Labs/streamBlockCipher/blowfish"
+	"strconv"
+)
+
+const (
+	MinCost     = 4
+	MaxCost     = 31
+	DefaultCost = 10
+
+	// Version2a and Version2b are the hash prefixes that can be produced, they compute the same hash
+	Version2a = "2a"
+	Version2b = "2b"
+
+	saltSize       = 16
+	encodedSalt    = 22
+	hashSize       = 23
+	encodedHash    = 31
+	prefixSize     = len("$2a$10$")
+	hashedPassword = prefixSize + encodedSalt + encodedHash
+)
+
+// Different types of error returned by the bcrypt functions
+var (
+	ErrMismatchedHashAndPassword = errors.New("bcrypt: hashedPassword is not the hash of the given password")
+	ErrHashTooShort              = errors.New("bcrypt: hashedSecret too short to be a bcrypted password")
+	ErrInvalidHash               = errors.New("bcrypt: hashedSecret is not a valid bcrypt hash")
+)
+
+// magicCipherData is "OrpheanBeholderScryDoubt", the text encrypted by bcrypt
+var magicCipherData = []byte("OrpheanBeholderScryDoubt")
+
+// bcryptEncoding is base64 with the alphabet of the original OpenBSD implementation, without padding
+var bcryptEncoding = base64.NewEncoding("./ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789").WithPadding(base64.NoPadding)
+
+// GenerateFromPassword returns the $2a$ bcrypt hash of the password at the given cost, with a random salt.
+// A cost below MinCost is replaced by DefaultCost, like golang.org/x/crypto/bcrypt does.
+func GenerateFromPassword(password []byte, cost int) ([]byte, error) {
+	return GenerateFromPasswordVersion(password, cost, Version2a)
+}
+
+// GenerateFromPasswordVersion is GenerateFromPassword with a chosen version prefix, Version2a or Version2b
+func GenerateFromPasswordVersion(password []byte, cost int, version string) ([]byte, error) {
+	if version != Version2a && version != Version2b {
+		return nil, fmt.Errorf("bcrypt: unsupported version %q", version)
+	}
+	if cost < MinCost {
+		cost = DefaultCost
+	}
+	if cost > MaxCost {
+		return nil, fmt.Errorf("bcrypt: cost %d is outside allowed range (%d,%d)", cost, MinCost, MaxCost)
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt(password, cost, salt)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("$%s$%02d$%s%s", version, cost, bcryptEncoding.EncodeToString(salt), hash)), nil
+}
+
+// CompareHashAndPassword compares a $2a$ or $2b$ bcrypt hash with a password. It returns nil on success,
+// ErrMismatchedHashAndPassword if the password is wrong.
+func CompareHashAndPassword(hashed, password []byte) error {
+	cost, salt, hash, err := decode(hashed)
+	if err != nil {
+		return err
+	}
+
+	computed, err := bcrypt(password, cost, salt)
+	if err != nil {
+		return err
+	}
+
+	if subtle.ConstantTimeCompare(computed, hash) != 1 {
+		return ErrMismatchedHashAndPassword
+	}
+	return nil
+}
+
+// Cost returns the cost a bcrypt hash was created with
+func Cost(hashed []byte) (int, error) {
+	cost, _, _, err := decode(hashed)
+	return cost, err
+}
+
+// decode splits a hash in its cost, raw salt and encoded hash
+func decode(hashed []byte) (int, []byte, []byte, error) {
+	if len(hashed) < hashedPassword {
+		return 0, nil, nil, ErrHashTooShort
+	}
+	if len(hashed) != hashedPassword || hashed[0] != '$' || hashed[3] != '$' || hashed[6] != '$' {
+		return 0, nil, nil, ErrInvalidHash
+	}
+
+	version := string(hashed[1:3])
+	if version != Version2a && version != Version2b {
+		return 0, nil, nil, ErrInvalidHash
+	}
+
+	cost, err := strconv.Atoi(string(hashed[4:6]))
+	if err != nil || cost < MinCost || cost > MaxCost {
+		return 0, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := bcryptEncoding.DecodeString(string(hashed[prefixSize : prefixSize+encodedSalt]))
+	if err != nil {
+		return 0, nil, nil, ErrInvalidHash
+	}
+	return cost, salt, hashed[prefixSize+encodedSalt:], nil
+}
+
+// bcrypt encrypts magicCipherData 64 times with the EksBlowfish state of the password, and encodes the first 23 bytes.
+// The password is terminated with a zero byte; only its first 72 bytes are used by the key schedule.
+func bcrypt(password []byte, cost int, salt []byte) ([]byte, error) {
+	key := make([]byte, len(password)+1)
+	copy(key, password)
+
+	eks, err := blowfish.NewEksBlowfish(cost, salt, key)
+	if err != nil {
+		return nil, err
+	}
+	block := eks.Block()
+
+	cipherData := make([]byte, len(magicCipherData))
+	copy(cipherData, magicCipherData)
+	for i := 0; i < len(cipherData); i += blowfish.BlockSize {
+		for j := 0; j < 64; j++ {
+			block.Encrypt(cipherData[i:i+blowfish.BlockSize], cipherData[i:i+blowfish.BlockSize])
+		}
+	}
+
+	hash := make([]byte, encodedHash)
+	bcryptEncoding.Encode(hash, cipherData[:hashSize])
+	return hash, nil
+}
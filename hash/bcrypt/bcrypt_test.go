@@ -0,0 +1,58 @@
+package bcrypt_test
+
+import (
+	"bytes"
+	"github.com/EliriaT/CS-Labs/hash/bcrypt"
+	xbcrypt "golang.org/x/crypto/bcrypt"
+	"testing"
+)
+
+var passwords = [][]byte{
+	[]byte(""),
+	[]byte("password"),
+	[]byte("a longer pass phrase with spaces"),
+	bytes.Repeat([]byte("x"), 72),
+}
+
+func TestVerifiedByLibrary(t *testing.T) {
+	for _, version := range []string{bcrypt.Version2a, bcrypt.Version2b} {
+		for _, password := range passwords {
+			hashed, err := bcrypt.GenerateFromPasswordVersion(password, 4, version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(hashed, []byte("$"+version+"$04$")) {
+				t.Fatalf("hash %s does not have the version %s", hashed, version)
+			}
+			if err := xbcrypt.CompareHashAndPassword(hashed, password); err != nil {
+				t.Fatalf("library rejected %s for %q: %v", hashed, password, err)
+			}
+			if err := xbcrypt.CompareHashAndPassword(hashed, []byte("wrong")); err == nil {
+				t.Fatalf("library accepted %s for a wrong password", hashed)
+			}
+		}
+	}
+}
+
+func TestVerifiesLibraryHashes(t *testing.T) {
+	for _, password := range passwords {
+		hashed, err := xbcrypt.GenerateFromPassword(password, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the library writes $2a$, the $2b$ hash of the same password only differs by its prefix
+		hashed2b := append([]byte("$2b$"), hashed[4:]...)
+
+		for _, h := range [][]byte{hashed, hashed2b} {
+			if err := bcrypt.CompareHashAndPassword(h, password); err != nil {
+				t.Fatalf("rejected %s for %q: %v", h, password, err)
+			}
+			if err := bcrypt.CompareHashAndPassword(h, []byte("wrong")); err != bcrypt.ErrMismatchedHashAndPassword {
+				t.Fatalf("CompareHashAndPassword with a wrong password = %v", err)
+			}
+			if cost, err := bcrypt.Cost(h); err != nil || cost != 4 {
+				t.Fatalf("Cost = %d, %v", cost, err)
+			}
+		}
+	}
+}
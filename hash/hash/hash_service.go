@@ -2,12 +2,23 @@ package hash
 
 import (
 	"fmt"
+	ownBcrypt "github.com/EliriaT/CS-Labs/hash/bcrypt"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// UseOwnBcrypt makes HashPassword and CheckPassword run on the bcrypt implementation of this project,
+// built on our Blowfish, instead of golang.org/x/crypto/bcrypt. Both produce compatible hashes.
+var UseOwnBcrypt = false
+
 // Returns the bcrypt hash of the password
 func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	var hashedPassword []byte
+	var err error
+	if UseOwnBcrypt {
+		hashedPassword, err = ownBcrypt.GenerateFromPassword([]byte(password), ownBcrypt.DefaultCost)
+	} else {
+		hashedPassword, err = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	}
 	if err != nil {
 		return "", fmt.Errorf("Failed to hash the password")
 	}
@@ -16,5 +27,8 @@ func HashPassword(password string) (string, error) {
 
 // Checks if the provided password is correct, nil if correct, error if wrong
 func CheckPassword(password string, hashedPassword string) error {
+	if UseOwnBcrypt {
+		return ownBcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	}
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
@@ -1,25 +1,53 @@
 package main
 
 import (
+	"encoding/base64"
+	"fmt"
 	"github.com/EliriaT/CS-Labs/api/config"
 	"github.com/EliriaT/CS-Labs/api/db"
 	"github.com/EliriaT/CS-Labs/api/server"
 	"github.com/EliriaT/CS-Labs/api/service"
 	"log"
 	"math/rand"
+	"os"
 	"time"
 )
 
 func main() {
 	rand.Seed(time.Now().UnixNano())
 
-	service.MakeCiphers()
-
 	configuration := config.LoadConfig()
 
-	store := db.NewStore()
+	// the tally commands are run by the operator, who holds the private tally key: the server never sees it
+	switch {
+	case len(os.Args) == 4 && os.Args[1] == "generate-tally-key":
+		generateTallyKey(os.Args[2], os.Args[3])
+		return
+	case len(os.Args) == 4 && os.Args[1] == "decrypt-tally":
+		decryptTally(os.Args[2], os.Args[3])
+		return
+	}
+
+	masterKey, err := service.DecodeMasterKey(configuration.MasterKey)
+	if err != nil {
+		log.Fatal("cannot load the master key: ", err)
+	}
+	keyring, err := service.NewKeyring(masterKey)
+	if err != nil {
+		log.Fatal("cannot load the master key: ", err)
+	}
+
+	store, err := db.OpenStore(configuration.StoreBackend, configuration.DataDir)
+	if err != nil {
+		log.Fatal("cannot open the store: ", err)
+	}
+
+	tallyKey, err := service.LoadTallyKey(configuration.TallyKeyFile)
+	if err != nil {
+		log.Fatal("cannot load the public tally key, generate it with generate-tally-key: ", err)
+	}
 
-	apiServer, err := server.NewServer(store, configuration, service.NewServerService(store))
+	apiServer, err := server.NewServer(store, configuration, service.NewServerService(store, keyring, tallyKey))
 
 	if err != nil {
 		log.Fatal("cannot create new server: ", err)
@@ -32,3 +60,24 @@ func main() {
 		log.Fatal("server can not be started. ", err)
 	}
 }
+
+// generateTallyKey writes a new tally key pair: the private key is kept by the operator, the public key is given to the server
+func generateTallyKey(privateKeyFile, publicKeyFile string) {
+	if err := service.GenerateTallyKey(privateKeyFile, publicKeyFile); err != nil {
+		log.Fatal("cannot generate the tally key: ", err)
+	}
+	fmt.Printf("private key written to %s, give %s to the server as TALLY_KEY_FILE\n", privateKeyFile, publicKeyFile)
+}
+
+// decryptTally prints the sum of the tally from the base64 encrypted_sum returned by GET /tally
+func decryptTally(privateKeyFile string, encoded string) {
+	encryptedSum, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		log.Fatal("the encrypted sum is not valid base64: ", err)
+	}
+	sum, err := service.DecryptTally(privateKeyFile, encryptedSum)
+	if err != nil {
+		log.Fatal("cannot decrypt the tally: ", err)
+	}
+	fmt.Println(sum)
+}
@@ -0,0 +1,35 @@
+package shamir
+
+// Arithmetic in GF(2^8) with the AES polynomial x^8 + x^4 + x^3 + x + 1.
+// Addition and subtraction are both xor. The operations avoid tables and branches on secret values,
+// so their timing does not depend on the shares.
+
+// gfMul multiplies a and b, adding the shifted a for every bit of b and reducing a when it overflows
+func gfMul(a, b byte) byte {
+	var product byte
+	for i := 0; i < 8; i++ {
+		product ^= a & -(b & 1)
+		// 0x1b when the top bit of a is set, 0 otherwise
+		reduce := 0x1b & -(a >> 7)
+		a = a<<1 ^ reduce
+		b >>= 1
+	}
+	return product
+}
+
+// gfInverse returns a^-1 as a^254, since a^255 = 1 for every non-zero a. The inverse of 0 is 0.
+func gfInverse(a byte) byte {
+	// a^254 = a^(2+4+8+16+32+64+128)
+	result := byte(1)
+	square := a
+	for i := 0; i < 7; i++ {
+		square = gfMul(square, square)
+		result = gfMul(result, square)
+	}
+	return result
+}
+
+// gfDiv divides a by b, b being non-zero
+func gfDiv(a, b byte) byte {
+	return gfMul(a, gfInverse(b))
+}
@@ -0,0 +1,24 @@
+package shamir
+
+import "testing"
+
+func TestGFMul(t *testing.T) {
+	// FIPS 197, section 4.2
+	if got := gfMul(0x57, 0x83); got != 0xc1 {
+		t.Fatalf("0x57 * 0x83 = %#x, want 0xc1", got)
+	}
+	if got := gfMul(0x57, 0x13); got != 0xfe {
+		t.Fatalf("0x57 * 0x13 = %#x, want 0xfe", got)
+	}
+}
+
+func TestGFInverse(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		if got := gfMul(byte(a), gfInverse(byte(a))); got != 1 {
+			t.Fatalf("%#x * %#x = %#x, want 1", a, gfInverse(byte(a)), got)
+		}
+	}
+	if gfInverse(0) != 0 {
+		t.Fatal("the inverse of 0 is not 0")
+	}
+}
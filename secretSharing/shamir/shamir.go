@@ -0,0 +1,157 @@
+// Package shamir splits a secret into n shares so that any k of them rebuild it,
+// while k-1 shares give no information about it (Shamir's secret sharing over GF(256)).
+package shamir
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+const (
+	// MaxShares is the number of distinct non-zero x coordinates in GF(256)
+	MaxShares = 255
+	// digestSize is the length of the secret digest shared along with the secret
+	digestSize = 8
+	// checksumSize is the length of the CRC-32 ending every share
+	checksumSize = 4
+	// headerSize is the length of the x coordinate and the threshold starting every share
+	headerSize = 2
+)
+
+var (
+	ErrInvalidThreshold = errors.New("shamir: threshold must be from 2 to the number of shares")
+	ErrInvalidParts     = errors.New("shamir: number of shares must be from 2 to 255")
+	ErrEmptySecret      = errors.New("shamir: secret must not be empty")
+	ErrTooFewShares     = errors.New("shamir: fewer shares than the threshold")
+	ErrInvalidShare     = errors.New("shamir: invalid share")
+	ErrDuplicateShare   = errors.New("shamir: duplicate share")
+	// ErrDigestMismatch is returned when the shares do not come from the same secret
+	ErrDigestMismatch = errors.New("shamir: the shares do not rebuild the secret")
+)
+
+// Split divides secret into parts shares, any threshold of which rebuild it.
+// A digest of the secret is shared with it so that Combine can detect a wrong result,
+// and every share ends with a checksum which detects a corrupted share.
+// A share is laid out as x, the threshold, then the y bytes, then the CRC-32 of the previous bytes.
+func Split(secret []byte, parts, threshold int) ([][]byte, error) {
+	if parts < 2 || parts > MaxShares {
+		return nil, ErrInvalidParts
+	}
+	if threshold < 2 || threshold > parts {
+		return nil, ErrInvalidThreshold
+	}
+	if len(secret) == 0 {
+		return nil, ErrEmptySecret
+	}
+
+	digest := secretDigest(secret)
+	value := append(append([]byte(nil), secret...), digest...)
+
+	shares := make([][]byte, parts)
+	for i := range shares {
+		shares[i] = make([]byte, headerSize+len(value)+checksumSize)
+		shares[i][0] = byte(i + 1)
+		shares[i][1] = byte(threshold)
+	}
+
+	// Every byte of the value is the constant term of its own random polynomial of degree threshold-1
+	coefficients := make([]byte, threshold-1)
+	for j, b := range value {
+		if _, err := rand.Read(coefficients); err != nil {
+			return nil, err
+		}
+		for _, share := range shares {
+			share[headerSize+j] = evaluate(b, coefficients, share[0])
+		}
+	}
+
+	for _, share := range shares {
+		binary.BigEndian.PutUint32(share[len(share)-checksumSize:], crc32.ChecksumIEEE(share[:len(share)-checksumSize]))
+	}
+	return shares, nil
+}
+
+// Combine rebuilds the secret from shares made by Split. The shares must be at least as many as the threshold
+// stored in them, ErrTooFewShares is returned otherwise.
+func Combine(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, ErrTooFewShares
+	}
+
+	size := len(shares[0])
+	if size < headerSize+1+digestSize+checksumSize {
+		return nil, fmt.Errorf("%w: share 0 is too short", ErrInvalidShare)
+	}
+	xs := make([]byte, len(shares))
+	seen := make(map[byte]bool, len(shares))
+	for i, share := range shares {
+		if len(share) != size {
+			return nil, fmt.Errorf("%w: share %d has a different length", ErrInvalidShare, i)
+		}
+		checksum := binary.BigEndian.Uint32(share[size-checksumSize:])
+		if crc32.ChecksumIEEE(share[:size-checksumSize]) != checksum {
+			return nil, fmt.Errorf("%w: share %d is corrupted", ErrInvalidShare, i)
+		}
+		x := share[0]
+		if x == 0 {
+			return nil, fmt.Errorf("%w: share %d has the x coordinate 0", ErrInvalidShare, i)
+		}
+		if seen[x] {
+			return nil, fmt.Errorf("%w: x = %d", ErrDuplicateShare, x)
+		}
+		if share[1] < 2 || share[1] != shares[0][1] {
+			return nil, fmt.Errorf("%w: share %d has a different or invalid threshold", ErrInvalidShare, i)
+		}
+		seen[x] = true
+		xs[i] = x
+	}
+	if len(shares) < int(shares[0][1]) {
+		return nil, ErrTooFewShares
+	}
+
+	// Lagrange interpolation at 0: value = sum of y_i * prod over j != i of x_j / (x_j - x_i)
+	weights := make([]byte, len(shares))
+	for i, xi := range xs {
+		weight := byte(1)
+		for j, xj := range xs {
+			if i != j {
+				weight = gfMul(weight, gfDiv(xj, xj^xi))
+			}
+		}
+		weights[i] = weight
+	}
+
+	value := make([]byte, size-headerSize-checksumSize)
+	for j := range value {
+		var b byte
+		for i, share := range shares {
+			b ^= gfMul(share[headerSize+j], weights[i])
+		}
+		value[j] = b
+	}
+
+	secret, digest := value[:len(value)-digestSize], value[len(value)-digestSize:]
+	if subtle.ConstantTimeCompare(secretDigest(secret), digest) != 1 {
+		return nil, ErrDigestMismatch
+	}
+	return secret, nil
+}
+
+// evaluate returns the value at x of the polynomial with the constant term intercept and the other coefficients, using Horner's method
+func evaluate(intercept byte, coefficients []byte, x byte) byte {
+	var result byte
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result = gfMul(result, x) ^ coefficients[i]
+	}
+	return gfMul(result, x) ^ intercept
+}
+
+func secretDigest(secret []byte) []byte {
+	sum := sha256.Sum256(secret)
+	return sum[:digestSize]
+}
@@ -0,0 +1,133 @@
+package shamir_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/EliriaT/CS-Labs/secretSharing/shamir"
+	"math/bits"
+	"testing"
+)
+
+// TestCombineSubsets combines every subset of the shares: the ones with at least threshold shares rebuild the secret,
+// the others are refused as too few
+func TestCombineSubsets(t *testing.T) {
+	secret := []byte("correct horse battery staple")
+	const parts, threshold = 6, 3
+
+	shares, err := shamir.Split(secret, parts, threshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares) != parts {
+		t.Fatalf("Split returned %d shares, want %d", len(shares), parts)
+	}
+
+	for mask := 1; mask < 1<<parts; mask++ {
+		var subset [][]byte
+		for i := 0; i < parts; i++ {
+			if mask&(1<<i) != 0 {
+				subset = append(subset, shares[i])
+			}
+		}
+
+		got, err := shamir.Combine(subset)
+		if bits.OnesCount(uint(mask)) < threshold {
+			if err != shamir.ErrTooFewShares {
+				t.Errorf("Combine of %d shares (mask %b) = %v, want ErrTooFewShares", len(subset), mask, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Combine of the shares in mask %b: %v", mask, err)
+			continue
+		}
+		if !bytes.Equal(got, secret) {
+			t.Errorf("Combine of the shares in mask %b = %q, want %q", mask, got, secret)
+		}
+	}
+}
+
+func TestSplitSizes(t *testing.T) {
+	tests := []struct {
+		secret    []byte
+		parts     int
+		threshold int
+	}{
+		{[]byte{0}, 2, 2},
+		{[]byte{0, 0, 0, 255}, 3, 2},
+		{bytes.Repeat([]byte{0xa5}, 1000), 10, 7},
+		{[]byte("all"), shamir.MaxShares, shamir.MaxShares},
+	}
+
+	for _, test := range tests {
+		shares, err := shamir.Split(test.secret, test.parts, test.threshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := shamir.Combine(shares[len(shares)-test.threshold:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, test.secret) {
+			t.Fatalf("%d-of-%d: Combine = %x, want %x", test.threshold, test.parts, got, test.secret)
+		}
+	}
+}
+
+func TestSplitInvalid(t *testing.T) {
+	tests := []struct {
+		secret    []byte
+		parts     int
+		threshold int
+		want      error
+	}{
+		{[]byte("s"), 1, 1, shamir.ErrInvalidParts},
+		{[]byte("s"), shamir.MaxShares + 1, 2, shamir.ErrInvalidParts},
+		{[]byte("s"), 5, 1, shamir.ErrInvalidThreshold},
+		{[]byte("s"), 5, 6, shamir.ErrInvalidThreshold},
+		{nil, 5, 3, shamir.ErrEmptySecret},
+	}
+	for _, test := range tests {
+		if _, err := shamir.Split(test.secret, test.parts, test.threshold); err != test.want {
+			t.Errorf("Split(%q, %d, %d) = %v, want %v", test.secret, test.parts, test.threshold, err, test.want)
+		}
+	}
+}
+
+// TestCorruptedShare changes one byte of a share at a time, which the checksum of the share must catch
+func TestCorruptedShare(t *testing.T) {
+	shares, err := shamir.Split([]byte("secret"), 4, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for position := range shares[1] {
+		subset := [][]byte{shares[0], append([]byte(nil), shares[1]...), shares[2]}
+		subset[1][position] ^= 0x40
+		if _, err := shamir.Combine(subset); !errors.Is(err, shamir.ErrInvalidShare) {
+			t.Errorf("byte %d of a share corrupted: Combine = %v, want ErrInvalidShare", position, err)
+		}
+	}
+
+	if _, err := shamir.Combine([][]byte{shares[0], shares[1][:len(shares[1])-1], shares[2]}); !errors.Is(err, shamir.ErrInvalidShare) {
+		t.Errorf("a truncated share: Combine = %v, want ErrInvalidShare", err)
+	}
+	if _, err := shamir.Combine([][]byte{shares[0], shares[0], shares[2]}); !errors.Is(err, shamir.ErrDuplicateShare) {
+		t.Errorf("a duplicate share: Combine = %v, want ErrDuplicateShare", err)
+	}
+}
+
+// TestMixedSecrets combines valid shares of two secrets of the same length and threshold
+func TestMixedSecrets(t *testing.T) {
+	first, err := shamir.Split([]byte("secret one"), 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := shamir.Split([]byte("secret two"), 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := shamir.Combine([][]byte{first[0], second[1]}); err != shamir.ErrDigestMismatch {
+		t.Fatalf("Combine of shares of two secrets = %v, want ErrDigestMismatch", err)
+	}
+}
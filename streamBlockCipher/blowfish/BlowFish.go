@@ -20,11 +20,7 @@ func (c *Blowfish) Encrypt(src []byte) ([]byte, error) {
 	var dst []byte
 	dst = make([]byte, 8)
 
-	l := uint32(src[0])<<24 | uint32(src[1])<<16 | uint32(src[2])<<8 | uint32(src[3])
-	r := uint32(src[4])<<24 | uint32(src[5])<<16 | uint32(src[6])<<8 | uint32(src[7])
-	l, r = encryptBlock(l, r, c)
-	dst[0], dst[1], dst[2], dst[3] = byte(l>>24), byte(l>>16), byte(l>>8), byte(l)
-	dst[4], dst[5], dst[6], dst[7] = byte(r>>24), byte(r>>16), byte(r>>8), byte(r)
+	encryptInto(dst, src, c)
 
 	return dst, nil
 }
@@ -35,15 +31,16 @@ func (c *Blowfish) Decrypt(src []byte) ([]byte, error) {
 	var dst []byte
 	dst = make([]byte, 8)
 
-	l := uint32(src[0])<<24 | uint32(src[1])<<16 | uint32(src[2])<<8 | uint32(src[3])
-	r := uint32(src[4])<<24 | uint32(src[5])<<16 | uint32(src[6])<<8 | uint32(src[7])
-	l, r = decryptBlock(l, r, c)
-	dst[0], dst[1], dst[2], dst[3] = byte(l>>24), byte(l>>16), byte(l>>8), byte(l)
-	dst[4], dst[5], dst[6], dst[7] = byte(r>>24), byte(r>>16), byte(r>>8), byte(r)
+	decryptInto(dst, src, c)
 
 	return dst, nil
 }
 
+// BlockSize returns the Blowfish block size, so that it can be used with the modes of operation
+func (c *Blowfish) BlockSize() int {
+	return BlockSize
+}
+
 func (c *Blowfish) Name() string {
 	return "Blowfish"
 }
@@ -54,6 +51,18 @@ func (k KeySizeError) Error() string {
 	return "invalid key size for blowfish" + strconv.Itoa(int(k))
 }
 
+type SaltSizeError int
+
+func (s SaltSizeError) Error() string {
+	return "invalid salt size for blowfish: " + strconv.Itoa(int(s))
+}
+
+type CostError int
+
+func (c CostError) Error() string {
+	return "invalid cost for eksblowfish: " + strconv.Itoa(int(c))
+}
+
 // NewBlowfish creates and returns a  Blowfish cipher.
 // The key argument should be the Blowfish key, from 4 to 56 bytes.
 func NewBlowfish(key []byte) (*Blowfish, error) {
@@ -66,6 +75,40 @@ func NewBlowfish(key []byte) (*Blowfish, error) {
 	return &result, nil
 }
 
+// NewSaltedBlowfish creates a Blowfish cipher whose key schedule is mixed with salt, as the first step of EksBlowfish.
+// The key can have any length from 1 byte, only its first 72 bytes are used.
+func NewSaltedBlowfish(key, salt []byte) (*Blowfish, error) {
+	if len(key) < 1 {
+		return nil, KeySizeError(len(key))
+	}
+	if len(salt) < 1 {
+		return nil, SaltSizeError(len(salt))
+	}
+	var result Blowfish
+	initCipher(&result)
+	ExpandKeyWithSalt(key, salt, &result)
+	return &result, nil
+}
+
+// NewEksBlowfish performs the expensive key setup of bcrypt: after the salted key schedule,
+// the key and the salt are expanded alternately 2^cost times.
+func NewEksBlowfish(cost int, salt, key []byte) (*Blowfish, error) {
+	if cost < 0 || cost > 31 {
+		return nil, CostError(cost)
+	}
+	result, err := NewSaltedBlowfish(key, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	rounds := uint64(1) << uint(cost)
+	for i := uint64(0); i < rounds; i++ {
+		ExpandKey(key, result)
+		ExpandKey(salt, result)
+	}
+	return result, nil
+}
+
 func initCipher(c *Blowfish) {
 	copy(c.p[0:], p[0:])
 	copy(c.s0[0:], s0[0:])
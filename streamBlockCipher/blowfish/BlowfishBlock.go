@@ -42,6 +42,48 @@ func ExpandKey(key []byte, c *Blowfish) {
 	}
 }
 
+// ExpandKeyWithSalt performs the salted key schedule of EksBlowfish: like ExpandKey, but the salt is
+// XORed into the block before every encryption that produces new subkeys.
+func ExpandKeyWithSalt(key []byte, salt []byte, c *Blowfish) {
+	j := 0
+	for i := 0; i < 18; i++ {
+		c.p[i] ^= getNextWord(key, &j)
+	}
+
+	j = 0
+	var l, r uint32
+	for i := 0; i < 18; i += 2 {
+		l ^= getNextWord(salt, &j)
+		r ^= getNextWord(salt, &j)
+		l, r = encryptBlock(l, r, c)
+		c.p[i], c.p[i+1] = l, r
+	}
+
+	for _, box := range []*[256]uint32{&c.s0, &c.s1, &c.s2, &c.s3} {
+		for i := 0; i < 256; i += 2 {
+			l ^= getNextWord(salt, &j)
+			r ^= getNextWord(salt, &j)
+			l, r = encryptBlock(l, r, c)
+			box[i], box[i+1] = l, r
+		}
+	}
+}
+
+// getNextWord returns the next big-endian uint32 of b, starting at pos and wrapping around the end of b
+func getNextWord(b []byte, pos *int) uint32 {
+	var w uint32
+	j := *pos
+	for i := 0; i < 4; i++ {
+		w = w<<8 | uint32(b[j])
+		j++
+		if j >= len(b) {
+			j = 0
+		}
+	}
+	*pos = j
+	return w
+}
+
 func encryptBlock(l, r uint32, c *Blowfish) (uint32, uint32) {
 	xl, xr := l, r
 	xl ^= c.p[0]
@@ -0,0 +1,58 @@
+package blowfish
+
+import "crypto/cipher"
+
+// cipherBlock exposes a Blowfish through the crypto/cipher.Block interface,
+// so that it can be used with cipher.NewCBCEncrypter, cipher.NewCTR and the other standard modes
+type cipherBlock struct {
+	c *Blowfish
+}
+
+// Block returns the Blowfish cipher as a crypto/cipher.Block.
+// Its Encrypt and Decrypt methods write into dst and do not allocate.
+func (c *Blowfish) Block() cipher.Block {
+	return cipherBlock{c: c}
+}
+
+func (b cipherBlock) BlockSize() int {
+	return BlockSize
+}
+
+// Encrypt encrypts the first block of src into dst. dst and src may overlap entirely.
+func (b cipherBlock) Encrypt(dst, src []byte) {
+	checkBlocks(dst, src)
+	encryptInto(dst, src, b.c)
+}
+
+// Decrypt decrypts the first block of src into dst. dst and src may overlap entirely.
+func (b cipherBlock) Decrypt(dst, src []byte) {
+	checkBlocks(dst, src)
+	decryptInto(dst, src, b.c)
+}
+
+func checkBlocks(dst, src []byte) {
+	if len(src) < BlockSize {
+		panic("blowfish: input not full block")
+	}
+	if len(dst) < BlockSize {
+		panic("blowfish: output not full block")
+	}
+}
+
+// encryptInto encrypts the 8-byte block src into dst
+func encryptInto(dst, src []byte, c *Blowfish) {
+	l := uint32(src[0])<<24 | uint32(src[1])<<16 | uint32(src[2])<<8 | uint32(src[3])
+	r := uint32(src[4])<<24 | uint32(src[5])<<16 | uint32(src[6])<<8 | uint32(src[7])
+	l, r = encryptBlock(l, r, c)
+	dst[0], dst[1], dst[2], dst[3] = byte(l>>24), byte(l>>16), byte(l>>8), byte(l)
+	dst[4], dst[5], dst[6], dst[7] = byte(r>>24), byte(r>>16), byte(r>>8), byte(r)
+}
+
+// decryptInto decrypts the 8-byte block src into dst
+func decryptInto(dst, src []byte, c *Blowfish) {
+	l := uint32(src[0])<<24 | uint32(src[1])<<16 | uint32(src[2])<<8 | uint32(src[3])
+	r := uint32(src[4])<<24 | uint32(src[5])<<16 | uint32(src[6])<<8 | uint32(src[7])
+	l, r = decryptBlock(l, r, c)
+	dst[0], dst[1], dst[2], dst[3] = byte(l>>24), byte(l>>16), byte(l>>8), byte(l)
+	dst[4], dst[5], dst[6], dst[7] = byte(r>>24), byte(r>>16), byte(r>>8), byte(r)
+}
@@ -0,0 +1,96 @@
+package blowfish_test
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"github.com/EliriaT/CS-Labs/streamBlockCipher/blowfish"
+	xblowfish "golang.org/x/crypto/blowfish"
+	"testing"
+)
+
+func newBlocks(t *testing.T, key []byte) (cipher.Block, cipher.Block) {
+	t.Helper()
+	ours, err := blowfish.NewBlowfish(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reference, err := xblowfish.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ours.Block(), reference
+}
+
+// TestBlockModes runs CBC and CTR from crypto/cipher over the block and checks the result against golang.org/x/crypto/blowfish
+func TestBlockModes(t *testing.T) {
+	for _, keySize := range []int{4, 16, 56} {
+		key := make([]byte, keySize)
+		rand.Read(key)
+		block, reference := newBlocks(t, key)
+		if block.BlockSize() != blowfish.BlockSize {
+			t.Fatalf("BlockSize = %d, want %d", block.BlockSize(), blowfish.BlockSize)
+		}
+
+		iv := make([]byte, blowfish.BlockSize)
+		rand.Read(iv)
+		plaintext := make([]byte, 20*blowfish.BlockSize)
+		rand.Read(plaintext)
+
+		got := make([]byte, len(plaintext))
+		want := make([]byte, len(plaintext))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(got, plaintext)
+		cipher.NewCBCEncrypter(reference, iv).CryptBlocks(want, plaintext)
+		if !bytes.Equal(got, want) {
+			t.Fatalf("%d-byte key: CBC encryption differs from x/crypto/blowfish", keySize)
+		}
+		// in place, as the modes do it
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(got, got)
+		if !bytes.Equal(got, plaintext) {
+			t.Fatalf("%d-byte key: CBC decryption does not give back the plaintext", keySize)
+		}
+
+		// a length which is not a whole number of blocks
+		plaintext = plaintext[:len(plaintext)-3]
+		got, want = got[:len(plaintext)], want[:len(plaintext)]
+		cipher.NewCTR(block, iv).XORKeyStream(got, plaintext)
+		cipher.NewCTR(reference, iv).XORKeyStream(want, plaintext)
+		if !bytes.Equal(got, want) {
+			t.Fatalf("%d-byte key: CTR differs from x/crypto/blowfish", keySize)
+		}
+		cipher.NewCTR(block, iv).XORKeyStream(got, got)
+		if !bytes.Equal(got, plaintext) {
+			t.Fatalf("%d-byte key: CTR does not give back the plaintext", keySize)
+		}
+	}
+}
+
+func TestBlockAllocations(t *testing.T) {
+	block, _ := newBlocks(t, []byte("some blowfish key"))
+	src := make([]byte, blowfish.BlockSize)
+	dst := make([]byte, blowfish.BlockSize)
+
+	if allocs := testing.AllocsPerRun(100, func() { block.Encrypt(dst, src) }); allocs != 0 {
+		t.Errorf("Encrypt allocates %v times per block", allocs)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { block.Decrypt(dst, src) }); allocs != 0 {
+		t.Errorf("Decrypt allocates %v times per block", allocs)
+	}
+}
+
+func TestBlockShortInput(t *testing.T) {
+	block, _ := newBlocks(t, []byte("some blowfish key"))
+	for name, call := range map[string]func(){
+		"short src": func() { block.Encrypt(make([]byte, 8), make([]byte, 7)) },
+		"short dst": func() { block.Decrypt(make([]byte, 7), make([]byte, 8)) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: no panic", name)
+				}
+			}()
+			call()
+		}()
+	}
+}
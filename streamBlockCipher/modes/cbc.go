@@ -0,0 +1,64 @@
+package modes
+
+// CBC is the Cipher Block Chaining mode: every plaintext block is XORed with the previous ciphertext block,
+// the first one with the IV, before being encrypted.
+type CBC struct {
+	block Block
+	iv    []byte
+}
+
+// NewCBC returns the CBC mode of block with the given IV. The input must be a multiple of the block size.
+// Every call to Encrypt starts again from the IV, so a new CBC with a fresh random IV should be used for every message.
+func NewCBC(block Block, iv []byte) (*CBC, error) {
+	if err := checkBlock(block); err != nil {
+		return nil, err
+	}
+	if err := checkIV(block, iv); err != nil {
+		return nil, err
+	}
+	return &CBC{block: block, iv: append([]byte(nil), iv...)}, nil
+}
+
+func (c *CBC) Encrypt(src []byte) ([]byte, error) {
+	size := c.block.BlockSize()
+	if len(src)%size != 0 {
+		return nil, ErrNotFullBlocks
+	}
+
+	dst := make([]byte, 0, len(src))
+	previous := c.iv
+	chained := make([]byte, size)
+	for i := 0; i < len(src); i += size {
+		xorBytes(chained, src[i:i+size], previous)
+		out, err := encryptBlock(c.block, chained)
+		if err != nil {
+			return nil, err
+		}
+		dst = append(dst, out...)
+		previous = out
+	}
+	return dst, nil
+}
+
+func (c *CBC) Decrypt(src []byte) ([]byte, error) {
+	size := c.block.BlockSize()
+	if len(src)%size != 0 {
+		return nil, ErrNotFullBlocks
+	}
+
+	dst := make([]byte, len(src))
+	previous := c.iv
+	for i := 0; i < len(src); i += size {
+		out, err := decryptBlock(c.block, src[i:i+size])
+		if err != nil {
+			return nil, err
+		}
+		xorBytes(dst[i:i+size], out, previous)
+		previous = src[i : i+size]
+	}
+	return dst, nil
+}
+
+func (c *CBC) Name() string {
+	return c.block.Name() + " CBC"
+}
@@ -0,0 +1,55 @@
+package modes
+
+// CFB is the Cipher Feedback mode with a feedback of one full block: the keystream is the encryption
+// of the previous ciphertext block, the first one being the IV. The input can have any length.
+type CFB struct {
+	block Block
+	iv    []byte
+}
+
+// NewCFB returns the CFB mode of block with the given IV.
+// Every call to Encrypt starts again from the IV, so a new CFB with a fresh random IV should be used for every message.
+func NewCFB(block Block, iv []byte) (*CFB, error) {
+	if err := checkBlock(block); err != nil {
+		return nil, err
+	}
+	if err := checkIV(block, iv); err != nil {
+		return nil, err
+	}
+	return &CFB{block: block, iv: append([]byte(nil), iv...)}, nil
+}
+
+func (c *CFB) Encrypt(src []byte) ([]byte, error) {
+	return c.crypt(src, false)
+}
+
+func (c *CFB) Decrypt(src []byte) ([]byte, error) {
+	return c.crypt(src, true)
+}
+
+func (c *CFB) crypt(src []byte, decrypt bool) ([]byte, error) {
+	size := c.block.BlockSize()
+	dst := make([]byte, len(src))
+	feedback := c.iv
+	for i := 0; i < len(src); i += size {
+		keystream, err := encryptBlock(c.block, feedback)
+		if err != nil {
+			return nil, err
+		}
+		end := i + size
+		if end > len(src) {
+			end = len(src)
+		}
+		xorBytes(dst[i:end], src[i:end], keystream)
+		if decrypt {
+			feedback = src[i:end]
+		} else {
+			feedback = dst[i:end]
+		}
+	}
+	return dst, nil
+}
+
+func (c *CFB) Name() string {
+	return c.block.Name() + " CFB"
+}
@@ -0,0 +1,58 @@
+package modes
+
+// CTR is the Counter mode: the keystream is the encryption of successive counter blocks, starting from
+// the initial counter block and incremented as a big-endian number. The input can have any length.
+type CTR struct {
+	block Block
+	nonce []byte
+}
+
+// NewCTR returns the CTR mode of block, nonce being the initial counter block.
+// The same nonce must never be used twice with the same key, since it would repeat the keystream.
+func NewCTR(block Block, nonce []byte) (*CTR, error) {
+	if err := checkBlock(block); err != nil {
+		return nil, err
+	}
+	if err := checkIV(block, nonce); err != nil {
+		return nil, err
+	}
+	return &CTR{block: block, nonce: append([]byte(nil), nonce...)}, nil
+}
+
+func (c *CTR) Encrypt(src []byte) ([]byte, error) {
+	size := c.block.BlockSize()
+	dst := make([]byte, len(src))
+	counter := append([]byte(nil), c.nonce...)
+	for i := 0; i < len(src); i += size {
+		keystream, err := encryptBlock(c.block, counter)
+		if err != nil {
+			return nil, err
+		}
+		end := i + size
+		if end > len(src) {
+			end = len(src)
+		}
+		xorBytes(dst[i:end], src[i:end], keystream)
+		increment(counter)
+	}
+	return dst, nil
+}
+
+// Decrypt is the same operation as Encrypt in CTR mode
+func (c *CTR) Decrypt(src []byte) ([]byte, error) {
+	return c.Encrypt(src)
+}
+
+func (c *CTR) Name() string {
+	return c.block.Name() + " CTR"
+}
+
+// increment adds one to counter, read as a big-endian number
+func increment(counter []byte) {
+	for i := len(counter) - 1; i >= 0; i-- {
+		counter[i]++
+		if counter[i] != 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package modes
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	ErrMACKeySize = errors.New("modes: MAC key must be at least 16 bytes")
+	ErrOpen       = errors.New("modes: message authentication failed")
+)
+
+// CTRHMAC is an authenticated encryption mode: the plaintext is encrypted in CTR mode, then
+// the additional data, the nonce and the ciphertext are authenticated with HMAC-SHA256 (encrypt-then-MAC).
+// It implements crypto/cipher.AEAD. The block key and the MAC key must be independent.
+type CTRHMAC struct {
+	block  Block
+	macKey []byte
+}
+
+// NewCTRHMAC returns the CTR-HMAC-SHA256 mode of block
+func NewCTRHMAC(block Block, macKey []byte) (*CTRHMAC, error) {
+	if err := checkBlock(block); err != nil {
+		return nil, err
+	}
+	if len(macKey) < 16 {
+		return nil, ErrMACKeySize
+	}
+	return &CTRHMAC{block: block, macKey: append([]byte(nil), macKey...)}, nil
+}
+
+// NonceSize returns the size of the nonce, which is the initial counter block
+func (c *CTRHMAC) NonceSize() int {
+	return c.block.BlockSize()
+}
+
+// Overhead returns the size of the tag appended to the ciphertext
+func (c *CTRHMAC) Overhead() int {
+	return sha256.Size
+}
+
+// Seal encrypts and authenticates plaintext, authenticates additionalData, and appends the ciphertext and its tag to dst.
+// It panics if the nonce has the wrong size, like the AEAD modes of the standard library.
+func (c *CTRHMAC) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	ctr, err := NewCTR(c.block, nonce)
+	if err != nil {
+		panic("modes: incorrect nonce length given to CTRHMAC")
+	}
+	ciphertext, err := ctr.Encrypt(plaintext)
+	if err != nil {
+		panic(err)
+	}
+	dst = append(dst, ciphertext...)
+	return append(dst, c.tag(nonce, ciphertext, additionalData)...)
+}
+
+// Open checks the tag of ciphertext before decrypting it, and appends the plaintext to dst.
+// Nothing is decrypted if the ciphertext, the nonce or the additional data were modified.
+func (c *CTRHMAC) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != c.NonceSize() {
+		panic("modes: incorrect nonce length given to CTRHMAC")
+	}
+	if len(ciphertext) < sha256.Size {
+		return nil, ErrOpen
+	}
+
+	tagStart := len(ciphertext) - sha256.Size
+	ciphertext, tag := ciphertext[:tagStart], ciphertext[tagStart:]
+	if !hmac.Equal(tag, c.tag(nonce, ciphertext, additionalData)) {
+		return nil, ErrOpen
+	}
+
+	ctr, err := NewCTR(c.block, nonce)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := ctr.Decrypt(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return append(dst, plaintext...), nil
+}
+
+func (c *CTRHMAC) Name() string {
+	return c.block.Name() + " CTR-HMAC-SHA256"
+}
+
+// tag authenticates the additional data, the nonce and the ciphertext, followed by the lengths of the
+// additional data and of the ciphertext so that the boundary between the fields can not be moved
+func (c *CTRHMAC) tag(nonce, ciphertext, additionalData []byte) []byte {
+	mac := hmac.New(sha256.New, c.macKey)
+	mac.Write(additionalData)
+	mac.Write(nonce)
+	mac.Write(ciphertext)
+	var lengths [16]byte
+	binary.BigEndian.PutUint64(lengths[:8], uint64(len(additionalData)))
+	binary.BigEndian.PutUint64(lengths[8:], uint64(len(ciphertext)))
+	mac.Write(lengths[:])
+	return mac.Sum(nil)
+}
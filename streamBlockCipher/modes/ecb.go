@@ -0,0 +1,44 @@
+package modes
+
+// ECB is the Electronic Codebook mode: every block is encrypted on its own.
+// Equal plaintext blocks give equal ciphertext blocks, so it should only be used to encrypt random data.
+type ECB struct {
+	block Block
+}
+
+// NewECB returns the ECB mode of block. The input must be a multiple of the block size.
+func NewECB(block Block) (*ECB, error) {
+	if err := checkBlock(block); err != nil {
+		return nil, err
+	}
+	return &ECB{block: block}, nil
+}
+
+func (e *ECB) Encrypt(src []byte) ([]byte, error) {
+	return e.crypt(src, encryptBlock)
+}
+
+func (e *ECB) Decrypt(src []byte) ([]byte, error) {
+	return e.crypt(src, decryptBlock)
+}
+
+func (e *ECB) crypt(src []byte, operation func(Block, []byte) ([]byte, error)) ([]byte, error) {
+	size := e.block.BlockSize()
+	if len(src)%size != 0 {
+		return nil, ErrNotFullBlocks
+	}
+
+	dst := make([]byte, 0, len(src))
+	for i := 0; i < len(src); i += size {
+		out, err := operation(e.block, src[i:i+size])
+		if err != nil {
+			return nil, err
+		}
+		dst = append(dst, out...)
+	}
+	return dst, nil
+}
+
+func (e *ECB) Name() string {
+	return e.block.Name() + " ECB"
+}
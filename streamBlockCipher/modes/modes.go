@@ -0,0 +1,65 @@
+package modes
+
+import (
+	"errors"
+	"github.com/EliriaT/CS-Labs/streamBlockCipher/cipherInterface"
+)
+
+// Different types of error returned when creating or using a mode of operation
+var (
+	ErrBlockSize      = errors.New("modes: block size must be 8 or 16 bytes")
+	ErrIVSize         = errors.New("modes: IV length must equal the block size")
+	ErrNotFullBlocks  = errors.New("modes: input is not a multiple of the block size")
+	ErrBlockOperation = errors.New("modes: block cipher returned a block of the wrong size")
+)
+
+// Block is a block cipher that encrypts exactly one block per call, like blowfish.Blowfish
+type Block interface {
+	cipherInterface.SymmetricCipher
+	BlockSize() int
+}
+
+func checkBlock(block Block) error {
+	if size := block.BlockSize(); size != 8 && size != 16 {
+		return ErrBlockSize
+	}
+	return nil
+}
+
+func checkIV(block Block, iv []byte) error {
+	if len(iv) != block.BlockSize() {
+		return ErrIVSize
+	}
+	return nil
+}
+
+// encryptBlock encrypts a single block, checking that the cipher kept its size
+func encryptBlock(block Block, src []byte) ([]byte, error) {
+	dst, err := block.Encrypt(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(dst) != block.BlockSize() {
+		return nil, ErrBlockOperation
+	}
+	return dst, nil
+}
+
+// decryptBlock decrypts a single block, checking that the cipher kept its size
+func decryptBlock(block Block, src []byte) ([]byte, error) {
+	dst, err := block.Decrypt(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(dst) != block.BlockSize() {
+		return nil, ErrBlockOperation
+	}
+	return dst, nil
+}
+
+// xorBytes sets dst[i] = a[i] ^ b[i] for the length of dst
+func xorBytes(dst, a, b []byte) {
+	for i := range dst {
+		dst[i] = a[i] ^ b[i]
+	}
+}
@@ -0,0 +1,190 @@
+package modes_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"github.com/EliriaT/CS-Labs/streamBlockCipher/blowfish"
+	"github.com/EliriaT/CS-Labs/streamBlockCipher/cipherInterface"
+	"github.com/EliriaT/CS-Labs/streamBlockCipher/modes"
+	"strings"
+	"testing"
+)
+
+// aesBlock adapts crypto/aes to modes.Block, so that the modes can be checked against the NIST vectors
+type aesBlock struct {
+	block cipher.Block
+}
+
+func (a aesBlock) Encrypt(src []byte) ([]byte, error) {
+	dst := make([]byte, aes.BlockSize)
+	a.block.Encrypt(dst, src)
+	return dst, nil
+}
+
+func (a aesBlock) Decrypt(src []byte) ([]byte, error) {
+	dst := make([]byte, aes.BlockSize)
+	a.block.Decrypt(dst, src)
+	return dst, nil
+}
+
+func (a aesBlock) BlockSize() int {
+	return aes.BlockSize
+}
+
+func (a aesBlock) Name() string {
+	return "AES"
+}
+
+func fromHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// The AES-128 examples of NIST SP 800-38A, appendix F
+const (
+	nistKey       = "2b7e151628aed2a6abf7158809cf4f3c"
+	nistIV        = "000102030405060708090a0b0c0d0e0f"
+	nistCounter   = "f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff"
+	nistPlaintext = `6bc1bee22e409f96e93d7e117393172a
+		ae2d8a571e03ac9c9eb76fac45af8e51
+		30c81c46a35ce411e5fbc1191a0a52ef
+		f69f2445df4f9b17ad2b417be66c3710`
+)
+
+func TestNISTVectors(t *testing.T) {
+	block, err := aes.NewCipher(fromHex(t, nistKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aesb := aesBlock{block}
+	iv := fromHex(t, nistIV)
+
+	tests := []struct {
+		name       string
+		mode       func() (cipherInterface.SymmetricCipher, error)
+		ciphertext string
+	}{
+		{"ECB", func() (cipherInterface.SymmetricCipher, error) { return modes.NewECB(aesb) }, `
+			3ad77bb40d7a3660a89ecaf32466ef97
+			f5d3d58503b9699de785895a96fdbaaf
+			43b1cd7f598ece23881b00e3ed030688
+			7b0c785e27e8ad3f8223207104725dd4`},
+		{"CBC", func() (cipherInterface.SymmetricCipher, error) { return modes.NewCBC(aesb, iv) }, `
+			7649abac8119b246cee98e9b12e9197d
+			5086cb9b507219ee95db113a917678b2
+			73bed6b8e3c1743b7116e69e22229516
+			3ff1caa1681fac09120eca307586e1a7`},
+		{"CFB", func() (cipherInterface.SymmetricCipher, error) { return modes.NewCFB(aesb, iv) }, `
+			3b3fd92eb72dad20333449f8e83cfb4a
+			c8a64537a0b3a93fcde3cdad9f1ce58b
+			26751f67a3cbb140b1808cf187a4f4df
+			c04b05357c5d1c0eeac4c66f9ff7f2e6`},
+		{"OFB", func() (cipherInterface.SymmetricCipher, error) { return modes.NewOFB(aesb, iv) }, `
+			3b3fd92eb72dad20333449f8e83cfb4a
+			7789508d16918f03f53c52dac54ed825
+			9740051e9c5fecf64344f7a82260edcc
+			304c6528f659c77866a510d9c1d6ae5e`},
+		{"CTR", func() (cipherInterface.SymmetricCipher, error) { return modes.NewCTR(aesb, fromHex(t, nistCounter)) }, `
+			874d6191b620e3261bef6864990db6ce
+			9806f66b7970fdff8617187bb9fffdff
+			5ae4df3edbd5d35e5b4f09020db03eab
+			1e031dda2fbe03d1792170a0f3009cee`},
+	}
+
+	plaintext := fromHex(t, nistPlaintext)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mode, err := test.mode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := fromHex(t, test.ciphertext)
+
+			got, err := mode.Encrypt(plaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("Encrypt = %x, want %x", got, want)
+			}
+
+			decrypted, err := mode.Decrypt(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Fatalf("Decrypt = %x, want %x", decrypted, plaintext)
+			}
+		})
+	}
+}
+
+func TestBlowfishRoundTrip(t *testing.T) {
+	block, err := blowfish.NewBlowfish([]byte("some blowfish key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	iv := []byte("8bytesIV")
+
+	streamModes := map[string]func() (cipherInterface.SymmetricCipher, error){
+		"CFB": func() (cipherInterface.SymmetricCipher, error) { return modes.NewCFB(block, iv) },
+		"OFB": func() (cipherInterface.SymmetricCipher, error) { return modes.NewOFB(block, iv) },
+		"CTR": func() (cipherInterface.SymmetricCipher, error) { return modes.NewCTR(block, iv) },
+	}
+	blockModes := map[string]func() (cipherInterface.SymmetricCipher, error){
+		"ECB": func() (cipherInterface.SymmetricCipher, error) { return modes.NewECB(block) },
+		"CBC": func() (cipherInterface.SymmetricCipher, error) { return modes.NewCBC(block, iv) },
+	}
+
+	for _, length := range []int{0, 1, 7, 8, 9, 1000} {
+		plaintext := make([]byte, length)
+		for i := range plaintext {
+			plaintext[i] = byte(i * 7)
+		}
+
+		for name, newMode := range streamModes {
+			checkRoundTrip(t, name, newMode, plaintext)
+		}
+		for name, newMode := range blockModes {
+			if length%blowfish.BlockSize != 0 {
+				mode, _ := newMode()
+				if _, err := mode.Encrypt(plaintext); err != modes.ErrNotFullBlocks {
+					t.Errorf("%s: Encrypt of %d bytes = %v, want ErrNotFullBlocks", name, length, err)
+				}
+				continue
+			}
+			checkRoundTrip(t, name, newMode, plaintext)
+		}
+	}
+}
+
+func checkRoundTrip(t *testing.T, name string, newMode func() (cipherInterface.SymmetricCipher, error), plaintext []byte) {
+	t.Helper()
+	mode, err := newMode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := mode.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("%s: Encrypt of %d bytes: %v", name, len(plaintext), err)
+	}
+	if len(ciphertext) != len(plaintext) {
+		t.Fatalf("%s: ciphertext of %d bytes for %d bytes", name, len(ciphertext), len(plaintext))
+	}
+	if len(plaintext) >= blowfish.BlockSize && bytes.Equal(ciphertext, plaintext) {
+		t.Fatalf("%s: ciphertext equals the plaintext", name)
+	}
+	decrypted, err := mode.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatalf("%s: Decrypt of %d bytes: %v", name, len(plaintext), err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Fatalf("%s: round trip of %d bytes failed", name, len(plaintext))
+	}
+}
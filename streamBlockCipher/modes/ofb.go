@@ -0,0 +1,48 @@
+package modes
+
+// OFB is the Output Feedback mode: the keystream is obtained by encrypting the IV again and again.
+// The input can have any length.
+type OFB struct {
+	block Block
+	iv    []byte
+}
+
+// NewOFB returns the OFB mode of block with the given IV.
+// Every call to Encrypt starts again from the IV, so a new OFB with a fresh random IV should be used for every message.
+func NewOFB(block Block, iv []byte) (*OFB, error) {
+	if err := checkBlock(block); err != nil {
+		return nil, err
+	}
+	if err := checkIV(block, iv); err != nil {
+		return nil, err
+	}
+	return &OFB{block: block, iv: append([]byte(nil), iv...)}, nil
+}
+
+func (o *OFB) Encrypt(src []byte) ([]byte, error) {
+	size := o.block.BlockSize()
+	dst := make([]byte, len(src))
+	keystream := o.iv
+	for i := 0; i < len(src); i += size {
+		var err error
+		keystream, err = encryptBlock(o.block, keystream)
+		if err != nil {
+			return nil, err
+		}
+		end := i + size
+		if end > len(src) {
+			end = len(src)
+		}
+		xorBytes(dst[i:end], src[i:end], keystream)
+	}
+	return dst, nil
+}
+
+// Decrypt is the same operation as Encrypt in OFB mode
+func (o *OFB) Decrypt(src []byte) ([]byte, error) {
+	return o.Encrypt(src)
+}
+
+func (o *OFB) Name() string {
+	return o.block.Name() + " OFB"
+}
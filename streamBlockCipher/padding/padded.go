@@ -0,0 +1,40 @@
+package padding
+
+import "github.com/EliriaT/CS-Labs/streamBlockCipher/cipherInterface"
+
+// Padded combines a block mode that needs whole blocks, like modes.CBC, with a padding scheme,
+// so that it can encrypt messages of any length
+type Padded struct {
+	mode      cipherInterface.SymmetricCipher
+	blockSize int
+	padding   Padding
+}
+
+// NewPadded returns mode padded with padding to blockSize bytes
+func NewPadded(mode cipherInterface.SymmetricCipher, blockSize int, padding Padding) (*Padded, error) {
+	if err := checkBlockSize(blockSize); err != nil {
+		return nil, err
+	}
+	return &Padded{mode: mode, blockSize: blockSize, padding: padding}, nil
+}
+
+func (p *Padded) Encrypt(src []byte) ([]byte, error) {
+	padded, err := p.padding.Pad(src, p.blockSize)
+	if err != nil {
+		return nil, err
+	}
+	return p.mode.Encrypt(padded)
+}
+
+// Decrypt returns ErrInvalidPadding whatever went wrong after decryption
+func (p *Padded) Decrypt(src []byte) ([]byte, error) {
+	decrypted, err := p.mode.Decrypt(src)
+	if err != nil {
+		return nil, err
+	}
+	return p.padding.Unpad(decrypted, p.blockSize)
+}
+
+func (p *Padded) Name() string {
+	return p.mode.Name() + " " + p.padding.Name()
+}
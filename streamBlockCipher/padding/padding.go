@@ -0,0 +1,201 @@
+package padding
+
+import (
+	"crypto/subtle"
+	"errors"
+)
+
+// Different types of error returned by the padding schemes
+var (
+	ErrBlockSize      = errors.New("padding: block size must be between 1 and 255")
+	ErrInvalidPadding = errors.New("padding: invalid padding")
+)
+
+// Padding extends data to a multiple of the block size, as the ECB and CBC modes require, and removes it again.
+// Unpad runs in a time that depends only on the length of its input, so that it can not be used as a padding oracle.
+type Padding interface {
+	Pad(src []byte, blockSize int) ([]byte, error)
+	Unpad(src []byte, blockSize int) ([]byte, error)
+	Name() string
+}
+
+var (
+	// PKCS7 fills n bytes of value n, as defined in RFC 5652
+	PKCS7 Padding = pkcs7{}
+	// ANSIX923 fills n-1 zero bytes followed by one byte of value n
+	ANSIX923 Padding = ansiX923{}
+	// ISO7816 appends the byte 0x80 followed by zero bytes, as defined in ISO/IEC 7816-4
+	ISO7816 Padding = iso7816{}
+	// Zero fills zero bytes up to the block boundary. It can not be removed unambiguously if the data ends with zero bytes.
+	Zero Padding = zero{}
+)
+
+func checkBlockSize(blockSize int) error {
+	if blockSize < 1 || blockSize > 255 {
+		return ErrBlockSize
+	}
+	return nil
+}
+
+// checkPadded verifies that src is made of whole blocks, and of at least one block
+func checkPadded(src []byte, blockSize int) error {
+	if err := checkBlockSize(blockSize); err != nil {
+		return err
+	}
+	if len(src) == 0 || len(src)%blockSize != 0 {
+		return ErrInvalidPadding
+	}
+	return nil
+}
+
+// paddingLength returns how many bytes are needed to reach the next block boundary, a whole block if src is already aligned
+func paddingLength(src []byte, blockSize int) int {
+	return blockSize - len(src)%blockSize
+}
+
+type pkcs7 struct{}
+
+func (pkcs7) Pad(src []byte, blockSize int) ([]byte, error) {
+	if err := checkBlockSize(blockSize); err != nil {
+		return nil, err
+	}
+	n := paddingLength(src, blockSize)
+	dst := make([]byte, len(src), len(src)+n)
+	copy(dst, src)
+	for i := 0; i < n; i++ {
+		dst = append(dst, byte(n))
+	}
+	return dst, nil
+}
+
+func (pkcs7) Unpad(src []byte, blockSize int) ([]byte, error) {
+	if err := checkPadded(src, blockSize); err != nil {
+		return nil, err
+	}
+	n := src[len(src)-1]
+	good := subtle.ConstantTimeLessOrEq(1, int(n)) & subtle.ConstantTimeLessOrEq(int(n), blockSize)
+
+	// Every byte of the last block is read, whatever the padding length is
+	for i := 1; i <= blockSize; i++ {
+		inPadding := subtle.ConstantTimeLessOrEq(i, int(n))
+		equal := subtle.ConstantTimeByteEq(src[len(src)-i], n)
+		good &= equal | (inPadding ^ 1)
+	}
+	return unpadded(src, int(n), good)
+}
+
+func (pkcs7) Name() string {
+	return "PKCS#7"
+}
+
+type ansiX923 struct{}
+
+func (ansiX923) Pad(src []byte, blockSize int) ([]byte, error) {
+	if err := checkBlockSize(blockSize); err != nil {
+		return nil, err
+	}
+	n := paddingLength(src, blockSize)
+	dst := make([]byte, len(src)+n)
+	copy(dst, src)
+	dst[len(dst)-1] = byte(n)
+	return dst, nil
+}
+
+func (ansiX923) Unpad(src []byte, blockSize int) ([]byte, error) {
+	if err := checkPadded(src, blockSize); err != nil {
+		return nil, err
+	}
+	n := src[len(src)-1]
+	good := subtle.ConstantTimeLessOrEq(1, int(n)) & subtle.ConstantTimeLessOrEq(int(n), blockSize)
+
+	for i := 2; i <= blockSize; i++ {
+		inPadding := subtle.ConstantTimeLessOrEq(i, int(n))
+		isZero := subtle.ConstantTimeByteEq(src[len(src)-i], 0)
+		good &= isZero | (inPadding ^ 1)
+	}
+	return unpadded(src, int(n), good)
+}
+
+func (ansiX923) Name() string {
+	return "ANSI X.923"
+}
+
+type iso7816 struct{}
+
+func (iso7816) Pad(src []byte, blockSize int) ([]byte, error) {
+	if err := checkBlockSize(blockSize); err != nil {
+		return nil, err
+	}
+	n := paddingLength(src, blockSize)
+	dst := make([]byte, len(src)+n)
+	copy(dst, src)
+	dst[len(src)] = 0x80
+	return dst, nil
+}
+
+func (iso7816) Unpad(src []byte, blockSize int) ([]byte, error) {
+	if err := checkPadded(src, blockSize); err != nil {
+		return nil, err
+	}
+
+	// Walking back through the last block, the padding is the zero bytes up to and including the first 0x80
+	n, found := 0, 0
+	for i := 1; i <= blockSize; i++ {
+		b := src[len(src)-i]
+		isMarker := subtle.ConstantTimeByteEq(b, 0x80) & (found ^ 1)
+		n = subtle.ConstantTimeSelect(isMarker, i, n)
+		// Before the marker only zero bytes are allowed, a non-zero byte means malformed padding
+		isInvalid := (subtle.ConstantTimeByteEq(b, 0) ^ 1) & (isMarker ^ 1) & (found ^ 1)
+		found |= isMarker | isInvalid
+	}
+	good := subtle.ConstantTimeLessOrEq(1, n)
+	return unpadded(src, n, good)
+}
+
+func (iso7816) Name() string {
+	return "ISO/IEC 7816-4"
+}
+
+type zero struct{}
+
+func (zero) Pad(src []byte, blockSize int) ([]byte, error) {
+	if err := checkBlockSize(blockSize); err != nil {
+		return nil, err
+	}
+	n := (blockSize - len(src)%blockSize) % blockSize
+	dst := make([]byte, len(src)+n)
+	copy(dst, src)
+	return dst, nil
+}
+
+func (zero) Unpad(src []byte, blockSize int) ([]byte, error) {
+	if err := checkBlockSize(blockSize); err != nil {
+		return nil, err
+	}
+	if len(src)%blockSize != 0 {
+		return nil, ErrInvalidPadding
+	}
+	if len(src) == 0 {
+		return src, nil
+	}
+
+	n, stop := 0, 0
+	for i := 1; i <= blockSize; i++ {
+		stop |= subtle.ConstantTimeByteEq(src[len(src)-i], 0) ^ 1
+		n = subtle.ConstantTimeSelect(stop, n, i)
+	}
+	return src[:len(src)-n], nil
+}
+
+func (zero) Name() string {
+	return "Zero"
+}
+
+// unpadded strips n bytes from src if the padding was found to be good.
+// The single error returned does not reveal which check has failed.
+func unpadded(src []byte, n int, good int) ([]byte, error) {
+	if good != 1 {
+		return nil, ErrInvalidPadding
+	}
+	return src[:len(src)-n], nil
+}
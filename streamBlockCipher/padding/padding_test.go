@@ -0,0 +1,152 @@
+package padding_test
+
+import (
+	"bytes"
+	"github.com/EliriaT/CS-Labs/streamBlockCipher/blowfish"
+	"github.com/EliriaT/CS-Labs/streamBlockCipher/modes"
+	"github.com/EliriaT/CS-Labs/streamBlockCipher/padding"
+	"testing"
+)
+
+var schemes = []padding.Padding{padding.PKCS7, padding.ANSIX923, padding.ISO7816, padding.Zero}
+
+func TestRoundTrip(t *testing.T) {
+	for _, scheme := range schemes {
+		for _, blockSize := range []int{8, 16} {
+			for length := 0; length <= 3*blockSize; length++ {
+				src := make([]byte, length)
+				for i := range src {
+					// no zero byte, so that the zero padding can be removed unambiguously
+					src[i] = byte(i%255 + 1)
+				}
+				padded, err := scheme.Pad(src, blockSize)
+				if err != nil {
+					t.Fatalf("%s: Pad: %v", scheme.Name(), err)
+				}
+				if len(padded)%blockSize != 0 {
+					t.Fatalf("%s: padded to %d bytes with blocks of %d", scheme.Name(), len(padded), blockSize)
+				}
+				unpadded, err := scheme.Unpad(padded, blockSize)
+				if err != nil {
+					t.Fatalf("%s: Unpad of %d bytes: %v", scheme.Name(), length, err)
+				}
+				if !bytes.Equal(unpadded, src) {
+					t.Fatalf("%s: round trip of %d bytes gave %x", scheme.Name(), length, unpadded)
+				}
+			}
+		}
+	}
+}
+
+func TestMalformedPadding(t *testing.T) {
+	tests := []struct {
+		scheme padding.Padding
+		src    []byte
+	}{
+		{padding.PKCS7, []byte{1, 2, 3, 4, 5, 6, 7, 0}},
+		{padding.PKCS7, []byte{1, 2, 3, 4, 5, 6, 7, 9}},
+		{padding.PKCS7, []byte{1, 2, 3, 4, 5, 3, 2, 3}},
+		{padding.PKCS7, []byte{1, 2, 3, 4, 5, 6, 1}},
+		{padding.PKCS7, []byte{}},
+		{padding.ANSIX923, []byte{1, 2, 3, 4, 5, 6, 7, 0}},
+		{padding.ANSIX923, []byte{1, 2, 3, 4, 5, 1, 0, 3}},
+		{padding.ANSIX923, []byte{1, 2, 3, 4, 5, 6, 7, 9}},
+		{padding.ANSIX923, []byte{}},
+		{padding.ISO7816, []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+		{padding.ISO7816, []byte{1, 2, 3, 4, 0x80, 0, 1, 0}},
+		{padding.ISO7816, []byte{0, 0, 0, 0, 0, 0, 0, 0}},
+		{padding.ISO7816, []byte{1, 2, 0x80}},
+		{padding.Zero, []byte{1, 2, 3}},
+	}
+
+	for _, test := range tests {
+		if _, err := test.scheme.Unpad(test.src, 8); err != padding.ErrInvalidPadding {
+			t.Errorf("%s: Unpad(%x) = %v, want ErrInvalidPadding", test.scheme.Name(), test.src, err)
+		}
+	}
+}
+
+// TestCBCPaddingOracle shows why a CBC decryption must not tell a bad padding apart from a bad message:
+// an attacker who only learns whether the padding of a forged ciphertext is valid recovers the plaintext.
+func TestCBCPaddingOracle(t *testing.T) {
+	block, err := blowfish.NewBlowfish([]byte("secret oracle key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	iv := []byte("initvect")
+	newCipher := func() *padding.Padded {
+		cbc, err := modes.NewCBC(block, iv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		padded, err := padding.NewPadded(cbc, blowfish.BlockSize, padding.PKCS7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return padded
+	}
+
+	secret := []byte("attack at dawn!!")
+	ciphertext, err := newCipher().Encrypt(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queries := 0
+	oracle := func(forged []byte) bool {
+		queries++
+		_, err := newCipher().Decrypt(forged)
+		return err == nil
+	}
+
+	size := blowfish.BlockSize
+	previous := ciphertext[:size]
+	target := ciphertext[size : 2*size]
+	recovered := recoverBlock(oracle, previous, target, size)
+
+	if want := secret[size : 2*size]; !bytes.Equal(recovered, want) {
+		t.Fatalf("recovered %q, want %q", recovered, want)
+	}
+	if queries > 256*size {
+		t.Fatalf("%d queries for one block", queries)
+	}
+}
+
+// recoverBlock decrypts target, the block following previous in a CBC ciphertext, with a padding oracle.
+// The forged block placed before target is decrypted as garbage, but it controls the XOR applied to target.
+func recoverBlock(oracle func([]byte) bool, previous, target []byte, size int) []byte {
+	// intermediate is the block cipher decryption of target, before the XOR of CBC
+	intermediate := make([]byte, size)
+	forged := make([]byte, 2*size)
+	copy(forged[size:], target)
+
+	for pad := 1; pad <= size; pad++ {
+		position := size - pad
+		for i := position + 1; i < size; i++ {
+			forged[i] = intermediate[i] ^ byte(pad)
+		}
+		for guess := 0; guess < 256; guess++ {
+			forged[position] = byte(guess)
+			if !oracle(forged) {
+				continue
+			}
+			if pad == 1 {
+				// the padding may be 02 02 by chance rather than 01: changing the byte before tells them apart
+				forged[position-1] ^= 0xff
+				valid := oracle(forged)
+				forged[position-1] ^= 0xff
+				if !valid {
+					continue
+				}
+			}
+			intermediate[position] = byte(guess) ^ byte(pad)
+			break
+		}
+	}
+
+	plaintext := make([]byte, size)
+	for i := range plaintext {
+		plaintext[i] = intermediate[i] ^ previous[i]
+	}
+	return plaintext
+}